@@ -0,0 +1,372 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountError is the failure of one account in an AccountManager
+// operation, Account is the account name or file it came from
+type AccountError struct {
+	Account string
+	Err     error
+}
+
+func (e *AccountError) Error() string {
+	return e.Account + ": " + e.Err.Error()
+}
+
+// Unwrap allows errors.Is and errors.As to see the underlying error
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// AccountErrors is every account that failed, one failing doesn't
+// stop the rest
+type AccountErrors []*AccountError
+
+func (e AccountErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d accounts failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Is allows errors.Is to match the error of any of the accounts
+func (e AccountErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountManager looks after a number of accounts, indexed by account
+// name and SteamID, and runs operations across them with a bounded
+// number of workers
+type AccountManager struct {
+	// Client accounts are bound to as they're added, nil leaves them be
+	Client *Client
+	// Workers is how many accounts talk to steam at once, defaults to 4
+	Workers int
+	// Store, if set, is where accounts are persisted as they're added,
+	// removed and their sessions refreshed
+	Store AccountStore
+
+	mu        sync.RWMutex
+	byName    map[string]*SteamGuardAccount
+	bySteamID map[SteamID]*SteamGuardAccount
+}
+
+// NewAccountManager returns an AccountManager binding its accounts to client
+func NewAccountManager(client *Client) *AccountManager {
+	return &AccountManager{
+		Client:  client,
+		Workers: 4,
+	}
+}
+
+// LoadDir adds every account file (*.json and *.maFile) in dir, files
+// that can't be loaded are returned as AccountErrors and the rest are
+// still added. They aren't put in Store, Save the ones you want there
+func (m *AccountManager) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs AccountErrors
+	for _, file := range files {
+		name := file.Name()
+		ext := filepath.Ext(name)
+		if file.IsDir() || name == "manifest.json" || (ext != ".json" && ext != ".maFile") {
+			continue
+		}
+
+		if err := m.loadFile(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, &AccountError{Account: name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LoadStore adds every account in Store
+func (m *AccountManager) LoadStore() error {
+	if m.Store == nil {
+		return fmt.Errorf("account manager has no store")
+	}
+
+	accounts, err := m.Store.List()
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if err := m.add(account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *AccountManager) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	account := &SteamGuardAccount{}
+	if err := account.Load(file); err != nil {
+		return err
+	}
+	return m.add(account)
+}
+
+// Add an account, replacing any with the same account name or SteamID,
+// and put it in Store
+func (m *AccountManager) Add(account *SteamGuardAccount) error {
+	if _, err := accountStoreKey(account); err != nil {
+		return err
+	}
+	if m.Store != nil {
+		if err := m.Store.Put(account); err != nil {
+			return err
+		}
+	}
+	return m.add(account)
+}
+
+func (m *AccountManager) add(account *SteamGuardAccount) error {
+	key := accountKey(account)
+	if key == "" {
+		return fmt.Errorf("account has neither an account name nor a steamid")
+	}
+
+	if m.Client != nil {
+		account.SetClient(m.Client)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.byName == nil {
+		m.byName = map[string]*SteamGuardAccount{}
+		m.bySteamID = map[SteamID]*SteamGuardAccount{}
+	}
+
+	if old := m.byName[key]; old != nil {
+		m.remove(old)
+	}
+	if steamID := accountSteamID(account); steamID != 0 {
+		if old := m.bySteamID[steamID]; old != nil {
+			m.remove(old)
+		}
+		m.bySteamID[steamID] = account
+	}
+	m.byName[key] = account
+	return nil
+}
+
+// Remove the account with the given account name, and delete it from Store
+func (m *AccountManager) Remove(name string) error {
+	if m.Store != nil {
+		if err := m.Store.Delete(name); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if account := m.byName[name]; account != nil {
+		m.remove(account)
+	}
+	return nil
+}
+
+// Save puts the account with the given account name in Store, for
+// after it's been changed
+func (m *AccountManager) Save(name string) error {
+	if m.Store == nil {
+		return fmt.Errorf("account manager has no store")
+	}
+	account := m.Get(name)
+	if account == nil {
+		return ErrAccountNotFound
+	}
+	return m.Store.Put(account)
+}
+
+func (m *AccountManager) remove(account *SteamGuardAccount) {
+	delete(m.byName, accountKey(account))
+	if steamID := accountSteamID(account); steamID != 0 {
+		delete(m.bySteamID, steamID)
+	}
+}
+
+// Get returns the account with the given account name, nil if there isn't one
+func (m *AccountManager) Get(name string) *SteamGuardAccount {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byName[name]
+}
+
+// GetBySteamID returns the account with the given SteamID, nil if there isn't one
+func (m *AccountManager) GetBySteamID(steamID SteamID) *SteamGuardAccount {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bySteamID[steamID]
+}
+
+// Accounts returns all the accounts ordered by account name
+func (m *AccountManager) Accounts() []*SteamGuardAccount {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]*SteamGuardAccount, 0, len(m.byName))
+	for _, account := range m.byName {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accountKey(accounts[i]) < accountKey(accounts[j])
+	})
+	return accounts
+}
+
+// GenerateCodes returns the current steam guard code of every account
+// by account name, accounts that failed are returned as AccountErrors
+func (m *AccountManager) GenerateCodes() (map[string]string, error) {
+	return m.GenerateCodesContext(context.Background())
+}
+
+// GenerateCodesContext is GenerateCodes with a context that can cancel
+// aligning the time with steam
+func (m *AccountManager) GenerateCodesContext(ctx context.Context) (map[string]string, error) {
+	var mu sync.Mutex
+	codes := map[string]string{}
+	err := m.Each(ctx, func(ctx context.Context, account *SteamGuardAccount) error {
+		steamTime, err := account.steamClient().TimeAligner().GetSteamTimeContext(ctx)
+		if err != nil {
+			return err
+		}
+		code, err := account.GenerateSteamGuardCodeForTime(steamTime)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		codes[accountKey(account)] = code
+		mu.Unlock()
+		return nil
+	})
+	return codes, err
+}
+
+// FetchConfirmations fetches the confirmations waiting on every account
+// by account name, accounts that failed are returned as AccountErrors
+func (m *AccountManager) FetchConfirmations() (map[string][]*Confirmation, error) {
+	return m.FetchConfirmationsContext(context.Background())
+}
+
+// FetchConfirmationsContext is FetchConfirmations with a context that
+// can cancel any in-flight requests to steam, fetching may refresh an
+// account's session so each is put back in Store afterwards
+func (m *AccountManager) FetchConfirmationsContext(ctx context.Context) (map[string][]*Confirmation, error) {
+	var mu sync.Mutex
+	confs := map[string][]*Confirmation{}
+	err := m.Each(ctx, func(ctx context.Context, account *SteamGuardAccount) error {
+		accountConfs, err := account.FetchConfirmationsContext(ctx)
+		if err != nil {
+			return err
+		}
+		if m.Store != nil {
+			if err := m.Store.Put(account); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		confs[accountKey(account)] = accountConfs
+		mu.Unlock()
+		return nil
+	})
+	return confs, err
+}
+
+// Each calls fn for every account using up to Workers goroutines,
+// accounts fn fails for are returned as AccountErrors
+func (m *AccountManager) Each(ctx context.Context, fn func(ctx context.Context, account *SteamGuardAccount) error) error {
+	workers := m.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	accounts := m.Accounts()
+	work := make(chan *SteamGuardAccount)
+	var mu sync.Mutex
+	var errs AccountErrors
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(accounts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for account := range work {
+				err := ctx.Err()
+				if err == nil {
+					err = fn(ctx, account)
+				}
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, &AccountError{Account: accountKey(account), Err: err})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, account := range accounts {
+		work <- account
+	}
+	close(work)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Account < errs[j].Account
+		})
+		return errs
+	}
+	return nil
+}
+
+// accountKey is the account name, or the steamid for accounts without one
+func accountKey(account *SteamGuardAccount) string {
+	if account.AccountName != "" {
+		return account.AccountName
+	}
+	steamID := accountSteamID(account)
+	return steamID.String()
+}
+
+func accountSteamID(account *SteamGuardAccount) SteamID {
+	if account.Session == nil {
+		return 0
+	}
+	return account.Session.SteamID
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccountManager(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("a") == "2" {
+			w.Write([]byte(`{"success":false,"needauth":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"conf":[{"id":"1","nonce":"1"}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.CommunityBase, _ = url.Parse(server.URL)
+	client.TimeAligner().aligned = true
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"one.maFile":    `{"account_name":"one","shared_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","identity_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","session":{"SteamID":1}}`,
+		"two.json":      `{"account_name":"two","shared_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","identity_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","session":{"SteamID":2}}`,
+		"three.json":    `{"account_name":"three","shared_secret":"not base64!","session":{"SteamID":3}}`,
+		"broken.json":   `{"account_name":`,
+		"manifest.json": `{"entries":[]}`,
+		"notes.txt":     `not an account`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewAccountManager(client)
+	manager.Workers = 2
+
+	var errs AccountErrors
+	if err := manager.LoadDir(dir); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Account != "broken.json" {
+		t.Fatalf("expected only broken.json to fail, got %v", err)
+	}
+
+	if len(manager.Accounts()) != 3 {
+		t.Fatalf("expected 3 accounts, got %d", len(manager.Accounts()))
+	}
+	if account := manager.GetBySteamID(2); account == nil || account != manager.Get("two") || account.steamClient() != client {
+		t.Errorf("expected to find two by name and steamid bound to the client, got %#v", account)
+	}
+
+	codes, err := manager.GenerateCodes()
+	if len(codes) != 2 || codes["one"] == "" || !errors.Is(err, ErrInvalidSharedSecret) {
+		t.Errorf("expected codes for one and two and three to fail, got %v %v", codes, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if codes, err := manager.GenerateCodesContext(ctx); len(codes) != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected no codes once the context is cancelled, got %v %v", codes, err)
+	}
+
+	confs, err := manager.FetchConfirmations()
+	if len(confs) != 1 || len(confs["one"]) != 1 {
+		t.Errorf("expected one's confirmation, got %v", confs)
+	}
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Account != "three" || !errors.Is(errs[1], ErrSessionExpired) {
+		t.Errorf("expected three and two to fail, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountStore keeps accounts, Get and Delete take either the account
+// name or the SteamID and Get returns ErrAccountNotFound for accounts
+// it doesn't have. Accounts are stored under their account name, or
+// SteamID if they don't have one
+type AccountStore interface {
+	Get(key string) (*SteamGuardAccount, error)
+	Put(account *SteamGuardAccount) error
+	List() ([]*SteamGuardAccount, error)
+	Delete(key string) error
+}
+
+// MemoryAccountStore keeps accounts in memory, the zero value is
+// ready to use
+type MemoryAccountStore struct {
+	mu       sync.Mutex
+	accounts map[string][]byte
+}
+
+// Get returns a copy of the account
+func (m *MemoryAccountStore) Get(key string) (*SteamGuardAccount, error) {
+	accounts, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	return findAccount(accounts, key)
+}
+
+// Put stores a copy of the account
+func (m *MemoryAccountStore) Put(account *SteamGuardAccount) error {
+	key, err := accountStoreKey(account)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.accounts == nil {
+		m.accounts = map[string][]byte{}
+	}
+	m.accounts[key] = b
+	return nil
+}
+
+// List returns copies of all the accounts ordered by account name
+func (m *MemoryAccountStore) List() ([]*SteamGuardAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accounts := make([]*SteamGuardAccount, 0, len(m.accounts))
+	for _, b := range m.accounts {
+		account := &SteamGuardAccount{}
+		if err := json.Unmarshal(b, account); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	sortAccounts(accounts)
+	return accounts, nil
+}
+
+// Delete removes the account
+func (m *MemoryAccountStore) Delete(key string) error {
+	account, err := m.Get(key)
+	if err == ErrAccountNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.accounts, accountKey(account))
+	return nil
+}
+
+// FileAccountStore keeps each account in a json file, the same as
+// SteamGuardAccount.Save writes, named for the account in Dir
+type FileAccountStore struct {
+	fileAccountStore
+}
+
+// NewFileAccountStore returns a store keeping its files in dir
+func NewFileAccountStore(dir string) *FileAccountStore {
+	return &FileAccountStore{fileAccountStore{
+		Dir: dir,
+		ext: ".json",
+		encode: func(account *SteamGuardAccount) ([]byte, error) {
+			buf := &bytes.Buffer{}
+			err := account.Save(buf)
+			return buf.Bytes(), err
+		},
+		decode: func(b []byte, account *SteamGuardAccount) error {
+			return account.Load(bytes.NewReader(b))
+		},
+	}}
+}
+
+// EncryptedFileAccountStore keeps each account in a file encrypted with
+// the passphrase, the same as SteamGuardAccount.SaveEncrypted writes,
+// named for the account in Dir
+type EncryptedFileAccountStore struct {
+	fileAccountStore
+}
+
+// NewEncryptedFileAccountStore returns a store keeping its files in dir
+// encrypted with passphrase
+func NewEncryptedFileAccountStore(dir, passphrase string) *EncryptedFileAccountStore {
+	return &EncryptedFileAccountStore{fileAccountStore{
+		Dir: dir,
+		ext: ".enc",
+		encode: func(account *SteamGuardAccount) ([]byte, error) {
+			buf := &bytes.Buffer{}
+			err := account.SaveEncrypted(buf, passphrase)
+			return buf.Bytes(), err
+		},
+		decode: func(b []byte, account *SteamGuardAccount) error {
+			return account.LoadEncrypted(bytes.NewReader(b), passphrase)
+		},
+	}}
+}
+
+// Rotate re-encrypts every account under a new passphrase and has the
+// store use it from then on. Every file is re-encrypted alongside the
+// original before any are replaced and files already under the new
+// passphrase are skipped, so if it fails part way it can be run again
+func (e *EncryptedFileAccountStore) Rotate(oldPassphrase, newPassphrase string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	paths, err := e.paths()
+	if err != nil {
+		return err
+	}
+
+	rotated := map[string]string{}
+	defer func() {
+		for _, tmp := range rotated {
+			os.Remove(tmp)
+		}
+	}()
+	for _, path := range paths {
+		b, err := rotateEncryptedFile(path, oldPassphrase, newPassphrase)
+		if errors.Is(err, ErrBadPasskey) && opensEncryptedFile(path, newPassphrase) {
+			continue
+		}
+		if err == nil {
+			rotated[path], err = writeTempFile(path, b)
+		}
+		if err != nil {
+			return &AccountError{Account: strings.TrimSuffix(filepath.Base(path), e.ext), Err: err}
+		}
+	}
+
+	for _, path := range paths {
+		if tmp, ok := rotated[path]; ok {
+			if err := renameSynced(tmp, path); err != nil {
+				return &AccountError{Account: strings.TrimSuffix(filepath.Base(path), e.ext), Err: err}
+			}
+		}
+	}
+
+	e.encode = func(account *SteamGuardAccount) ([]byte, error) {
+		buf := &bytes.Buffer{}
+		err := account.SaveEncrypted(buf, newPassphrase)
+		return buf.Bytes(), err
+	}
+	e.decode = func(b []byte, account *SteamGuardAccount) error {
+		return account.LoadEncrypted(bytes.NewReader(b), newPassphrase)
+	}
+	return nil
+}
+
+// fileAccountStore is a directory of one file per account, how they're
+// written is up to encode and decode
+type fileAccountStore struct {
+	Dir string
+
+	mu     sync.Mutex
+	ext    string
+	encode func(account *SteamGuardAccount) ([]byte, error)
+	decode func(b []byte, account *SteamGuardAccount) error
+}
+
+// Get returns the account
+func (f *fileAccountStore) Get(key string) (*SteamGuardAccount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// most of the time it's the account name and the file is right there
+	if account, err := f.load(f.path(key)); err == nil {
+		return account, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	accounts, err := f.list()
+	if err != nil {
+		return nil, err
+	}
+	return findAccount(accounts, key)
+}
+
+// Put stores the account
+func (f *fileAccountStore) Put(account *SteamGuardAccount) error {
+	key, err := accountStoreKey(account)
+	if err != nil {
+		return err
+	}
+
+	// encode under the lock, Rotate swaps the passphrase out
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := f.encode(account)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path(key), b)
+}
+
+// List returns all the accounts ordered by account name
+func (f *fileAccountStore) List() ([]*SteamGuardAccount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.list()
+}
+
+// Delete removes the account
+func (f *fileAccountStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path(key))
+	if err == nil {
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	accounts, err := f.list()
+	if err != nil {
+		return err
+	}
+	account, err := findAccount(accounts, key)
+	if err == ErrAccountNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(f.path(accountKey(account)))
+}
+
+func (f *fileAccountStore) list() ([]*SteamGuardAccount, error) {
+	paths, err := f.paths()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*SteamGuardAccount, 0, len(paths))
+	for _, path := range paths {
+		account, err := f.load(path)
+		if err != nil {
+			return nil, &AccountError{Account: strings.TrimSuffix(filepath.Base(path), f.ext), Err: err}
+		}
+		accounts = append(accounts, account)
+	}
+	sortAccounts(accounts)
+	return accounts, nil
+}
+
+func (f *fileAccountStore) paths() ([]string, error) {
+	return filepath.Glob(filepath.Join(f.Dir, "*"+f.ext))
+}
+
+func (f *fileAccountStore) load(path string) (*SteamGuardAccount, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	account := &SteamGuardAccount{}
+	return account, f.decode(b, account)
+}
+
+func (f *fileAccountStore) path(key string) string {
+	return filepath.Join(f.Dir, filepath.Base(key)+f.ext)
+}
+
+// accountStoreKey is the key the account is stored under, account
+// names are safe to use as file names but make sure
+func accountStoreKey(account *SteamGuardAccount) (string, error) {
+	key := accountKey(account)
+	if key == "" {
+		return "", fmt.Errorf("account has neither an account name nor a steamid")
+	}
+	if key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("account name %q can't be stored", key)
+	}
+	return key, nil
+}
+
+// findAccount returns the account with the given account name or SteamID
+func findAccount(accounts []*SteamGuardAccount, key string) (*SteamGuardAccount, error) {
+	for _, account := range accounts {
+		if account.AccountName == key {
+			return account, nil
+		}
+	}
+	for _, account := range accounts {
+		if steamID := accountSteamID(account); steamID != 0 && steamID.String() == key {
+			return account, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+func sortAccounts(accounts []*SteamGuardAccount) {
+	sort.Slice(accounts, func(i, j int) bool {
+		return accountKey(accounts[i]) < accountKey(accounts[j])
+	})
+}
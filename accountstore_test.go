@@ -0,0 +1,251 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestAccountStores(t *testing.T) {
+	defer func(kdf envelopeKDFParams) { envelopeKDF = kdf }(envelopeKDF)
+	envelopeKDF = envelopeKDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	for name, store := range map[string]AccountStore{
+		"memory":    &MemoryAccountStore{},
+		"file":      NewFileAccountStore(filepath.Join(t.TempDir(), "accounts")),
+		"encrypted": NewEncryptedFileAccountStore(t.TempDir(), "passphrase"),
+	} {
+		one := &SteamGuardAccount{AccountName: "one", SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=", Session: &SessionData{SteamID: 1}}
+		two := &SteamGuardAccount{SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=", Session: &SessionData{SteamID: 2}}
+
+		if _, err := store.Get("one"); !errors.Is(err, ErrAccountNotFound) {
+			t.Errorf("%s: expected ErrAccountNotFound, got %v", name, err)
+		}
+		if err := store.Put(&SteamGuardAccount{AccountName: "../escape"}); err == nil {
+			t.Errorf("%s: expected a path in the account name to be refused", name)
+		}
+
+		for _, account := range []*SteamGuardAccount{one, two} {
+			if err := store.Put(account); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		one.SharedSecret = "changed after put"
+		if account, err := store.Get("one"); err != nil || account.SharedSecret != "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=" {
+			t.Errorf("%s: expected the stored copy of one, got %#v %v", name, account, err)
+		}
+		if account, err := store.Get("1"); err != nil || account.AccountName != "one" {
+			t.Errorf("%s: expected one by steamid, got %#v %v", name, account, err)
+		}
+		if account, err := store.Get("2"); err != nil || accountSteamID(account) != 2 {
+			t.Errorf("%s: expected the account without a name by steamid, got %#v %v", name, account, err)
+		}
+
+		accounts, err := store.List()
+		if err != nil || len(accounts) != 2 || accounts[1].AccountName != "one" {
+			t.Errorf("%s: expected both accounts, got %v %v", name, accounts, err)
+		}
+
+		if err := store.Delete("1"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := store.Delete("missing"); err != nil {
+			t.Errorf("%s: deleting a missing account should be a no-op, got %v", name, err)
+		}
+		if accounts, _ := store.List(); len(accounts) != 1 {
+			t.Errorf("%s: expected one account left, got %d", name, len(accounts))
+		}
+	}
+}
+
+func TestEncryptedFileAccountStore(t *testing.T) {
+	defer func(kdf envelopeKDFParams) { envelopeKDF = kdf }(envelopeKDF)
+	envelopeKDF = envelopeKDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	dir := t.TempDir()
+	store := NewEncryptedFileAccountStore(dir, "old")
+	if err := store.Put(&SteamGuardAccount{AccountName: "bot", SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=", RevocationCode: "R12345"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "bot.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("R12345")) {
+		t.Fatalf("account written in the clear %s", b)
+	}
+
+	if _, err := NewEncryptedFileAccountStore(dir, "wrong").Get("bot"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+
+	if err := store.Rotate("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if account, err := store.Get("bot"); err != nil || account.RevocationCode != "R12345" {
+		t.Errorf("expected the store to use the new passphrase, got %#v %v", account, err)
+	}
+	if _, err := NewEncryptedFileAccountStore(dir, "new").Get("bot"); err != nil {
+		t.Errorf("expected the file to be under the new passphrase, got %v", err)
+	}
+}
+
+func TestEncryptedFileAccountStoreRotateConcurrently(t *testing.T) {
+	defer func(kdf envelopeKDFParams) { envelopeKDF = kdf }(envelopeKDF)
+	envelopeKDF = envelopeKDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	dir := t.TempDir()
+	store := NewEncryptedFileAccountStore(dir, "old")
+	if err := store.Put(&SteamGuardAccount{AccountName: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := store.Put(&SteamGuardAccount{AccountName: "bot" + strconv.Itoa(i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	if err := store.Rotate("old", "new"); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+
+	// every file has to be under the new passphrase no matter when it was put
+	accounts, err := NewEncryptedFileAccountStore(dir, "new").List()
+	if err != nil || len(accounts) != 9 {
+		t.Errorf("expected all 9 accounts under the new passphrase, got %d %v", len(accounts), err)
+	}
+}
+
+func TestEncryptedFileAccountStoreRotateResume(t *testing.T) {
+	defer func(kdf envelopeKDFParams) { envelopeKDF = kdf }(envelopeKDF)
+	envelopeKDF = envelopeKDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	dir := t.TempDir()
+	store := NewEncryptedFileAccountStore(dir, "old")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := store.Put(&SteamGuardAccount{AccountName: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewEncryptedFileAccountStore(dir, "other").Put(&SteamGuardAccount{AccountName: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var accountErr *AccountError
+	if err := store.Rotate("old", "new"); !errors.As(err, &accountErr) || accountErr.Account != "b" || !errors.Is(err, ErrBadPasskey) {
+		t.Fatalf("expected b to fail the rotation, got %v", err)
+	}
+	if accounts, err := store.List(); err == nil || len(accounts) != 0 {
+		t.Errorf("expected b to still fail to list, got %v", err)
+	}
+	if accounts, _ := NewEncryptedFileAccountStore(dir, "new").List(); len(accounts) != 0 {
+		t.Errorf("expected nothing to be rotated when one account fails")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 3 {
+		t.Errorf("expected the rotated copies to be cleaned up, got %d files", len(files))
+	}
+
+	// fix b and pretend a crash happened after a was renamed into place
+	if err := store.Put(&SteamGuardAccount{AccountName: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RotateEncryptedFile(filepath.Join(dir, "a.enc"), "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Rotate("old", "new"); err != nil {
+		t.Fatalf("expected the rotation to resume, got %v", err)
+	}
+	if accounts, err := NewEncryptedFileAccountStore(dir, "new").List(); err != nil || len(accounts) != 3 {
+		t.Errorf("expected all 3 accounts under the new passphrase, got %d %v", len(accounts), err)
+	}
+	if accounts, err := store.List(); err != nil || len(accounts) != 3 {
+		t.Errorf("expected the store to use the new passphrase, got %d %v", len(accounts), err)
+	}
+}
+
+func TestAccountManagerStore(t *testing.T) {
+	store := &MemoryAccountStore{}
+	store.Put(&SteamGuardAccount{AccountName: "stored", Session: &SessionData{SteamID: 1}})
+
+	manager := NewAccountManager(nil)
+	manager.Store = store
+	if err := manager.LoadStore(); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Get("stored") == nil {
+		t.Fatal("expected the stored account to be loaded")
+	}
+
+	if err := manager.Add(&SteamGuardAccount{AccountName: "added", Session: &SessionData{SteamID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("added"); err != nil {
+		t.Errorf("expected added to be stored, got %v", err)
+	}
+
+	manager.Get("added").RevocationCode = "R12345"
+	if err := manager.Save("added"); err != nil {
+		t.Fatal(err)
+	}
+	if account, _ := store.Get("added"); account == nil || account.RevocationCode != "R12345" {
+		t.Errorf("expected the change to be saved, got %#v", account)
+	}
+	if err := manager.Save("missing"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	if err := manager.Remove("stored"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("stored"); !errors.Is(err, ErrAccountNotFound) || manager.Get("stored") != nil {
+		t.Errorf("expected stored to be removed from both, got %v", err)
+	}
+}
+
+func TestAccountManagerStoreOnlyExplicitly(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "loaded.maFile"), []byte(`{"account_name":"loaded","session":{"SteamID":1}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &MemoryAccountStore{}
+	manager := NewAccountManager(nil)
+	manager.Store = store
+
+	if err := manager.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Get("loaded") == nil {
+		t.Fatal("expected loaded to be added")
+	}
+	if err := manager.Add(&SteamGuardAccount{}); err == nil {
+		t.Error("expected an account without a name or steamid to be refused")
+	}
+	if accounts, _ := store.List(); len(accounts) != 0 {
+		t.Errorf("expected nothing to be stored, got %d accounts", len(accounts))
+	}
+
+	if err := manager.Save("loaded"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("loaded"); err != nil {
+		t.Errorf("expected loaded to be stored once saved, got %v", err)
+	}
+}
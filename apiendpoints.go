@@ -6,13 +6,20 @@ package steamauth
 
 import "net/url"
 
-// APIEndpoints is storage for a bunch of common endpoints
-var APIEndpoints = struct {
+// Endpoints is storage for a bunch of common endpoints
+type Endpoints struct {
 	SteamAPIBase       *url.URL
 	CommunityBase      *url.URL
 	TwoFactorTimeQuery *url.URL
-}{
-	SteamAPIBase:       &url.URL{Scheme: "https", Host: "api.steampowered.com"},
-	CommunityBase:      &url.URL{Scheme: "https", Host: "steamcommunity.com"},
-	TwoFactorTimeQuery: &url.URL{Scheme: "https", Host: "api.steampowered.com", Path: "/ITwoFactorService/QueryTime/v0001"},
+}
+
+// APIEndpoints is storage for the endpoints used by the DefaultClient
+var APIEndpoints = defaultEndpoints()
+
+func defaultEndpoints() Endpoints {
+	return Endpoints{
+		SteamAPIBase:       &url.URL{Scheme: "https", Host: "api.steampowered.com"},
+		CommunityBase:      &url.URL{Scheme: "https", Host: "steamcommunity.com"},
+		TwoFactorTimeQuery: &url.URL{Scheme: "https", Host: "api.steampowered.com", Path: "/ITwoFactorService/QueryTime/v0001"},
+	}
 }
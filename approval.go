@@ -0,0 +1,363 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ApprovalStatus is where an ApprovalRequest is at
+type ApprovalStatus string
+
+// Various ApprovalStatus
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalDenied   ApprovalStatus = "denied"
+	ApprovalExpired  ApprovalStatus = "expired"
+)
+
+// ApprovalRequest is a confirmation waiting on approvers to decide
+type ApprovalRequest struct {
+	ID           string             `json:"id"`
+	Confirmation *Confirmation      `json:"confirmation"`
+	Status       ApprovalStatus     `json:"status"`
+	CreatedAt    time.Time          `json:"created_at"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	Decisions    []ApprovalDecision `json:"decisions"`
+	Audit        []ApprovalEvent    `json:"audit"`
+}
+
+// ApprovalDecision is one approver's say on a request
+type ApprovalDecision struct {
+	Approver string    `json:"approver"`
+	Approve  bool      `json:"approve"`
+	At       time.Time `json:"at"`
+}
+
+// ApprovalEvent is an entry in a request's audit trail
+type ApprovalEvent struct {
+	At       time.Time `json:"at"`
+	Approver string    `json:"approver,omitempty"`
+	Event    string    `json:"event"`
+	Err      string    `json:"error,omitempty"`
+}
+
+// ApprovalStore keeps approval requests, Get returns
+// ErrApprovalNotFound for requests it doesn't have
+type ApprovalStore interface {
+	Get(id string) (*ApprovalRequest, error)
+	Put(req *ApprovalRequest) error
+	List() ([]*ApprovalRequest, error)
+	Delete(id string) error
+}
+
+// ApprovalQueue holds confirmations until enough approvers agree,
+// only then are they accepted (or rejected) on steam
+type ApprovalQueue struct {
+	Account *SteamGuardAccount
+	Store   ApprovalStore
+
+	// Approvers allowed to decide, there have to be some otherwise
+	// anyone could make up enough names for a quorum
+	Approvers []string
+	// Quorum is how many approvals it takes to accept, defaults to 2,
+	// it can't be more than there are Approvers
+	Quorum int
+	// RejectQuorum is how many denials it takes to reject, defaults to 1,
+	// it can't be more than there are Approvers
+	RejectQuorum int
+	// Expiry is how long a request waits for a quorum, defaults to 24 hours
+	Expiry time.Duration
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewApprovalQueue returns a two person approval queue for the account
+func NewApprovalQueue(account *SteamGuardAccount, store ApprovalStore) *ApprovalQueue {
+	return &ApprovalQueue{
+		Account:      account,
+		Store:        store,
+		Quorum:       2,
+		RejectQuorum: 1,
+		Expiry:       24 * time.Hour,
+	}
+}
+
+// Submit queues the confirmation for approval, submitting one that
+// is already queued returns the existing request
+func (q *ApprovalQueue) Submit(conf *Confirmation) (*ApprovalRequest, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	req, err := q.Store.Get(conf.ConfirmationID)
+	if err == nil {
+		return req, nil
+	}
+	if err != ErrApprovalNotFound {
+		return nil, err
+	}
+
+	now := q.clock()
+	req = &ApprovalRequest{
+		ID:           conf.ConfirmationID,
+		Confirmation: conf,
+		Status:       ApprovalPending,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(q.expiry()),
+	}
+	req.audit(now, "", "submitted", nil)
+	q.Account.steamClient().logf("approval: confirmation %s (%s) submitted", conf.ConfirmationID, conf.Headline)
+
+	return req, q.Store.Put(req)
+}
+
+// Approve records the approver's approval, once there's a quorum
+// the confirmation is accepted
+func (q *ApprovalQueue) Approve(id, approver string) (*ApprovalRequest, error) {
+	return q.ApproveContext(context.Background(), id, approver)
+}
+
+// ApproveContext is Approve with a context that can cancel any
+// in-flight requests to steam
+func (q *ApprovalQueue) ApproveContext(ctx context.Context, id, approver string) (*ApprovalRequest, error) {
+	return q.decide(ctx, id, approver, true)
+}
+
+// Deny records the approver's denial, once there's a quorum the
+// confirmation is rejected
+func (q *ApprovalQueue) Deny(id, approver string) (*ApprovalRequest, error) {
+	return q.DenyContext(context.Background(), id, approver)
+}
+
+// DenyContext is Deny with a context that can cancel any in-flight
+// requests to steam
+func (q *ApprovalQueue) DenyContext(ctx context.Context, id, approver string) (*ApprovalRequest, error) {
+	return q.decide(ctx, id, approver, false)
+}
+
+// Resolve acts on a request that has a quorum but couldn't be sent
+// to steam at the time, eg because the session had expired
+func (q *ApprovalQueue) Resolve(id string) (*ApprovalRequest, error) {
+	return q.ResolveContext(context.Background(), id)
+}
+
+// ResolveContext is Resolve with a context that can cancel any
+// in-flight requests to steam
+func (q *ApprovalQueue) ResolveContext(ctx context.Context, id string) (*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	req, err := q.open(id)
+	if err != nil {
+		return req, err
+	}
+
+	err = q.resolve(ctx, req)
+	if putErr := q.Store.Put(req); err == nil {
+		err = putErr
+	}
+	return req, err
+}
+
+// Pending returns the requests still waiting on a decision, expiring
+// any that have run out of time along the way
+func (q *ApprovalQueue) Pending() ([]*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	reqs, err := q.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*ApprovalRequest
+	for _, req := range reqs {
+		if req.Status != ApprovalPending {
+			continue
+		}
+		if q.expire(req) {
+			if err := q.Store.Put(req); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		pending = append(pending, req)
+	}
+
+	return pending, nil
+}
+
+func (q *ApprovalQueue) decide(ctx context.Context, id, approver string, approve bool) (*ApprovalRequest, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	if !q.isApprover(approver) {
+		return nil, ErrNotApprover
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	req, err := q.open(id)
+	if err != nil {
+		return req, err
+	}
+
+	for _, decision := range req.Decisions {
+		if decision.Approver == approver {
+			return req, ErrAlreadyDecided
+		}
+	}
+
+	now := q.clock()
+	req.Decisions = append(req.Decisions, ApprovalDecision{Approver: approver, Approve: approve, At: now})
+	req.audit(now, approver, iif(approve, "approved", "denied"), nil)
+	q.Account.steamClient().logf("approval: confirmation %s %s by %s", id, iif(approve, "approved", "denied"), approver)
+
+	err = q.resolve(ctx, req)
+	if putErr := q.Store.Put(req); err == nil {
+		err = putErr
+	}
+	return req, err
+}
+
+// open returns the request if it's still pending, saving it
+// as expired if it has run out of time
+func (q *ApprovalQueue) open(id string) (*ApprovalRequest, error) {
+	req, err := q.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.expire(req) {
+		if err := q.Store.Put(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Status != ApprovalPending {
+		return req, ErrApprovalClosed
+	}
+
+	return req, nil
+}
+
+// resolve accepts or rejects the confirmation if there's a quorum
+func (q *ApprovalQueue) resolve(ctx context.Context, req *ApprovalRequest) error {
+	approvals, denials := 0, 0
+	for _, decision := range req.Decisions {
+		if decision.Approve {
+			approvals++
+		} else {
+			denials++
+		}
+	}
+
+	var err error
+	var status ApprovalStatus
+	switch {
+	case denials >= q.rejectQuorum():
+		status = ApprovalDenied
+		err = q.Account.RejectConfirmationContext(ctx, req.Confirmation)
+	case approvals >= q.quorum():
+		status = ApprovalApproved
+		err = q.Account.AcceptConfirmationContext(ctx, req.Confirmation)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		req.audit(q.clock(), "", "unable to send "+string(status)+" to steam", err)
+		q.Account.steamClient().logf("approval: unable to send confirmation %s %s to steam: %s", req.ID, status, err)
+		return err
+	}
+
+	req.Status = status
+	req.audit(q.clock(), "", "sent "+string(status)+" to steam", nil)
+	q.Account.steamClient().logf("approval: confirmation %s %s", req.ID, status)
+	return nil
+}
+
+// expire marks the request expired if it has run out of time, the
+// confirmation is left alone on steam
+func (q *ApprovalQueue) expire(req *ApprovalRequest) bool {
+	now := q.clock()
+	if req.Status != ApprovalPending || req.ExpiresAt.IsZero() || now.Before(req.ExpiresAt) {
+		return false
+	}
+	req.Status = ApprovalExpired
+	req.audit(now, "", "expired", nil)
+	q.Account.steamClient().logf("approval: confirmation %s expired", req.ID)
+	return true
+}
+
+func (q *ApprovalQueue) isApprover(approver string) bool {
+	if approver == "" {
+		return false
+	}
+	for _, a := range q.Approvers {
+		if a == approver {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *ApprovalQueue) clock() time.Time {
+	if q.now == nil {
+		return time.Now()
+	}
+	return q.now()
+}
+
+// validate makes sure the approvers can reach a quorum either way,
+// otherwise every request would sit there until it expired
+func (q *ApprovalQueue) validate() error {
+	if len(q.Approvers) == 0 {
+		return ErrNoApprovers
+	}
+	if q.quorum() > len(q.Approvers) || q.rejectQuorum() > len(q.Approvers) {
+		return fmt.Errorf("%w: quorum of %d to approve and %d to deny from %d approvers", ErrQuorumUnreachable, q.quorum(), q.rejectQuorum(), len(q.Approvers))
+	}
+	return nil
+}
+
+func (q *ApprovalQueue) expiry() time.Duration {
+	if q.Expiry <= 0 {
+		return 24 * time.Hour
+	}
+	return q.Expiry
+}
+
+func (q *ApprovalQueue) quorum() int {
+	if q.Quorum <= 0 {
+		return 2
+	}
+	return q.Quorum
+}
+
+func (q *ApprovalQueue) rejectQuorum() int {
+	if q.RejectQuorum <= 0 {
+		return 1
+	}
+	return q.RejectQuorum
+}
+
+func (r *ApprovalRequest) audit(at time.Time, approver, event string, err error) {
+	entry := ApprovalEvent{At: at, Approver: approver, Event: event}
+	if err != nil {
+		entry.Err = err.Error()
+	}
+	r.Audit = append(r.Audit, entry)
+}
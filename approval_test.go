@@ -0,0 +1,166 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newApprovalTestQueue(t *testing.T, store ApprovalStore) (*ApprovalQueue, map[string]string, func()) {
+	answered := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/ajaxop", func(w http.ResponseWriter, r *http.Request) {
+		answered[r.URL.Query().Get("cid")] = r.URL.Query().Get("op")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+
+	queue := NewApprovalQueue(account, store)
+	queue.Approvers = []string{"alice", "bob", "carol"}
+	return queue, answered, done
+}
+
+func TestApprovalQueueQuorum(t *testing.T) {
+	for name, store := range map[string]ApprovalStore{
+		"memory": &MemoryApprovalStore{},
+		"file":   NewFileApprovalStore(filepath.Join(t.TempDir(), "approvals.json")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			queue, answered, done := newApprovalTestQueue(t, store)
+			defer done()
+
+			if _, err := queue.Submit(&Confirmation{ConfirmationID: "1", ConfirmationKey: "key"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := queue.Approve("1", "mallory"); !errors.Is(err, ErrNotApprover) {
+				t.Errorf("expected ErrNotApprover, got %v", err)
+			}
+
+			req, err := queue.Approve("1", "alice")
+			if err != nil || req.Status != ApprovalPending || len(answered) != 0 {
+				t.Fatalf("expected one approval to leave it pending, got %s %v %v", req.Status, answered, err)
+			}
+
+			if _, err := queue.Approve("1", "alice"); !errors.Is(err, ErrAlreadyDecided) {
+				t.Errorf("expected ErrAlreadyDecided, got %v", err)
+			}
+
+			req, err = queue.Approve("1", "bob")
+			if err != nil || req.Status != ApprovalApproved || answered["1"] != "allow" {
+				t.Fatalf("expected two approvals to accept, got %s %v %v", req.Status, answered, err)
+			}
+
+			if _, err := queue.Deny("1", "carol"); !errors.Is(err, ErrApprovalClosed) {
+				t.Errorf("expected ErrApprovalClosed, got %v", err)
+			}
+
+			stored, err := store.Get("1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			events := []string{}
+			for _, event := range stored.Audit {
+				events = append(events, event.Approver+" "+event.Event)
+			}
+			expected := []string{" submitted", "alice approved", "bob approved", " sent approved to steam"}
+			if len(events) != len(expected) {
+				t.Fatalf("audit mismatched %q", events)
+			}
+			for i := range expected {
+				if events[i] != expected[i] {
+					t.Errorf("audit mismatched %q", events)
+				}
+			}
+		})
+	}
+}
+
+func TestApprovalQueueNoApprovers(t *testing.T) {
+	queue, answered, done := newApprovalTestQueue(t, &MemoryApprovalStore{})
+	defer done()
+
+	if _, err := queue.Submit(&Confirmation{ConfirmationID: "1", ConfirmationKey: "key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// one caller making up two names mustn't reach a quorum
+	queue.Approvers = nil
+	for _, approver := range []string{"a", "b"} {
+		if _, err := queue.Approve("1", approver); !errors.Is(err, ErrNoApprovers) {
+			t.Errorf("expected ErrNoApprovers for %s, got %v", approver, err)
+		}
+	}
+	if len(answered) != 0 {
+		t.Errorf("expected nothing to be answered, got %v", answered)
+	}
+
+	if _, err := queue.Submit(&Confirmation{ConfirmationID: "2", ConfirmationKey: "key"}); !errors.Is(err, ErrNoApprovers) {
+		t.Errorf("expected ErrNoApprovers, got %v", err)
+	}
+}
+
+func TestApprovalQueueQuorumUnreachable(t *testing.T) {
+	queue, answered, done := newApprovalTestQueue(t, &MemoryApprovalStore{})
+	defer done()
+
+	if _, err := queue.Submit(&Confirmation{ConfirmationID: "1", ConfirmationKey: "key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	queue.Approvers = []string{"alice", "bob"}
+	queue.Quorum = 3
+	if _, err := queue.Submit(&Confirmation{ConfirmationID: "2", ConfirmationKey: "key"}); !errors.Is(err, ErrQuorumUnreachable) {
+		t.Errorf("expected ErrQuorumUnreachable, got %v", err)
+	}
+	if _, err := queue.Approve("1", "alice"); !errors.Is(err, ErrQuorumUnreachable) {
+		t.Errorf("expected ErrQuorumUnreachable, got %v", err)
+	}
+
+	queue.Quorum = 2
+	queue.RejectQuorum = 3
+	if _, err := queue.Deny("1", "alice"); !errors.Is(err, ErrQuorumUnreachable) {
+		t.Errorf("expected ErrQuorumUnreachable, got %v", err)
+	}
+	if len(answered) != 0 {
+		t.Errorf("expected nothing to be answered, got %v", answered)
+	}
+}
+
+func TestApprovalQueueDenyAndExpire(t *testing.T) {
+	queue, answered, done := newApprovalTestQueue(t, &MemoryApprovalStore{})
+	defer done()
+
+	now := time.Unix(1700000000, 0)
+	queue.now = func() time.Time { return now }
+
+	queue.Submit(&Confirmation{ConfirmationID: "1"})
+	queue.Submit(&Confirmation{ConfirmationID: "2"})
+
+	if req, err := queue.Deny("1", "carol"); err != nil || req.Status != ApprovalDenied || answered["1"] != "cancel" {
+		t.Errorf("expected a denial to reject, got %s %v %v", req.Status, answered, err)
+	}
+
+	pending, err := queue.Pending()
+	if err != nil || len(pending) != 1 || pending[0].ID != "2" {
+		t.Fatalf("expected 2 to be pending, got %v %v", pending, err)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if pending, _ := queue.Pending(); len(pending) != 0 {
+		t.Errorf("expected nothing pending after expiry, got %v", pending)
+	}
+	if _, err := queue.Approve("2", "alice"); !errors.Is(err, ErrApprovalClosed) {
+		t.Errorf("expected ErrApprovalClosed, got %v", err)
+	}
+	if req, _ := queue.Store.Get("2"); req.Status != ApprovalExpired || answered["2"] != "" {
+		t.Errorf("expected 2 to expire untouched, got %s %v", req.Status, answered)
+	}
+}
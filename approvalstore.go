@@ -0,0 +1,179 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// MemoryApprovalStore keeps approval requests in memory, the zero
+// value is ready to use
+type MemoryApprovalStore struct {
+	mu   sync.Mutex
+	reqs map[string][]byte
+}
+
+// Get returns a copy of the request
+func (m *MemoryApprovalStore) Get(id string) (*ApprovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.reqs[id]
+	if !ok {
+		return nil, ErrApprovalNotFound
+	}
+	req := &ApprovalRequest{}
+	return req, json.Unmarshal(b, req)
+}
+
+// Put stores a copy of the request
+func (m *MemoryApprovalStore) Put(req *ApprovalRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reqs == nil {
+		m.reqs = map[string][]byte{}
+	}
+	m.reqs[req.ID] = b
+	return nil
+}
+
+// List returns copies of all the requests, oldest first
+func (m *MemoryApprovalStore) List() ([]*ApprovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reqs := make([]*ApprovalRequest, 0, len(m.reqs))
+	for _, b := range m.reqs {
+		req := &ApprovalRequest{}
+		if err := json.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	sortApprovalRequests(reqs)
+	return reqs, nil
+}
+
+// Delete removes the request
+func (m *MemoryApprovalStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reqs, id)
+	return nil
+}
+
+// FileApprovalStore keeps approval requests in a json file, it's
+// rewritten in full on every change so is meant for the handful
+// of confirmations a high value account sees
+type FileApprovalStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileApprovalStore returns a store backed by the file at path
+func NewFileApprovalStore(path string) *FileApprovalStore {
+	return &FileApprovalStore{Path: path}
+}
+
+// Get returns the request
+func (f *FileApprovalStore) Get(id string) (*ApprovalRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reqs, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	req, ok := reqs[id]
+	if !ok {
+		return nil, ErrApprovalNotFound
+	}
+	return req, nil
+}
+
+// Put stores the request
+func (f *FileApprovalStore) Put(req *ApprovalRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reqs, err := f.load()
+	if err != nil {
+		return err
+	}
+	reqs[req.ID] = req
+	return f.save(reqs)
+}
+
+// List returns all the requests, oldest first
+func (f *FileApprovalStore) List() ([]*ApprovalRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reqs, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*ApprovalRequest, 0, len(reqs))
+	for _, req := range reqs {
+		ret = append(ret, req)
+	}
+	sortApprovalRequests(ret)
+	return ret, nil
+}
+
+// Delete removes the request
+func (f *FileApprovalStore) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reqs, err := f.load()
+	if err != nil {
+		return err
+	}
+	delete(reqs, id)
+	return f.save(reqs)
+}
+
+func (f *FileApprovalStore) load() (map[string]*ApprovalRequest, error) {
+	reqs := map[string]*ApprovalRequest{}
+
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return reqs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reqs, json.Unmarshal(b, &reqs)
+}
+
+func (f *FileApprovalStore) save(reqs map[string]*ApprovalRequest) error {
+	b, err := json.MarshalIndent(reqs, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(f.Path, b)
+}
+
+func sortApprovalRequests(reqs []*ApprovalRequest) {
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].CreatedAt.Before(reqs[j].CreatedAt)
+	})
+}
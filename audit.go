@@ -0,0 +1,278 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditAction is what was done with the authenticator
+type AuditAction string
+
+// Various AuditActions
+const (
+	AuditAcceptConfirmation      AuditAction = "accept confirmation"
+	AuditRejectConfirmation      AuditAction = "reject confirmation"
+	AuditDeactivateAuthenticator AuditAction = "deactivate authenticator"
+	AuditFinalizeAuthenticator   AuditAction = "finalize authenticator"
+	AuditApproveAuthSession      AuditAction = "approve auth session"
+	AuditDenyAuthSession         AuditAction = "deny auth session"
+)
+
+// AuditEntry is one action in an audit log, Target is the confirmation
+// or auth session client id acted on and Err is set if steam (or the
+// network) said no
+type AuditEntry struct {
+	Seq      uint64      `json:"seq"`
+	Time     time.Time   `json:"time"`
+	Action   AuditAction `json:"action"`
+	Account  string      `json:"account,omitempty"`
+	SteamID  SteamID     `json:"steamid,omitempty"`
+	Target   string      `json:"target,omitempty"`
+	Detail   string      `json:"detail,omitempty"`
+	Err      string      `json:"error,omitempty"`
+	PrevHash string      `json:"prev_hash"`
+	Hash     string      `json:"hash"`
+}
+
+// AuditSink records authenticator actions, set one on Client.Audit
+type AuditSink interface {
+	Record(entry *AuditEntry) error
+}
+
+// audit records the action if the client has a sink, the action has
+// already happened by now so a sink that fails is only logged
+func (c *Client) audit(s *SteamGuardAccount, action AuditAction, target, detail string, err error) {
+	if c.Audit == nil {
+		return
+	}
+
+	entry := &AuditEntry{
+		Time:    time.Now(),
+		Action:  action,
+		Account: s.AccountName,
+		Target:  target,
+		Detail:  detail,
+	}
+	if s.Session != nil {
+		entry.SteamID = s.Session.SteamID
+	}
+	if err != nil {
+		entry.Err = err.Error()
+	}
+
+	if err := c.Audit.Record(entry); err != nil {
+		c.logf("audit: unable to record %s %s: %s", action, target, err)
+	}
+}
+
+// FileAuditLog is an append only JSONL audit log, every entry carries
+// the hash of the one before it so edits and removals break the chain.
+// With a Key the hashes are HMAC-SHA256 so whoever can write the file
+// can't rewrite it and chain the hashes back up without the key too
+type FileAuditLog struct {
+	Path string
+	Key  []byte
+
+	mu       sync.Mutex
+	loaded   bool
+	seq      uint64
+	prevHash string
+}
+
+// NewFileAuditLog returns an audit log appending to the file at path
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{Path: path}
+}
+
+// NewKeyedFileAuditLog returns an audit log appending to the file at
+// path with its chain keyed by key
+func NewKeyedFileAuditLog(path string, key []byte) *FileAuditLog {
+	return &FileAuditLog{Path: path, Key: key}
+}
+
+// Record appends the entry, filling in its Seq, PrevHash and Hash. The
+// existing log is verified before the first entry is added so a log
+// that has been tampered with isn't extended
+func (f *FileAuditLog) Record(entry *AuditEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.loaded {
+		last, err := VerifyAuditLogFileHead(f.Path, f.Key, 0, "")
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if last != nil {
+			f.seq, f.prevHash = last.Seq, last.Hash
+		}
+		f.loaded = true
+	}
+
+	entry.Seq = f.seq + 1
+	entry.Time = entry.Time.UTC()
+	entry.PrevHash = f.prevHash
+	hash, err := entry.hash(f.Key)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	f.seq, f.prevHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// Head returns the sequence number and hash of the last entry written,
+// keep them somewhere else and pass them to VerifyAuditLogFileHead to
+// catch entries removed from the end
+func (f *FileAuditLog) Head() (uint64, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq, f.prevHash
+}
+
+// VerifyAuditLogFile is VerifyAuditLog for the file at path
+func VerifyAuditLogFile(path string) (*AuditEntry, error) {
+	return VerifyAuditLogFileHead(path, nil, 0, "")
+}
+
+// VerifyAuditLog walks the hash chain of an unkeyed log and returns the
+// last entry, nil for an empty log. Edits, reordering and entries removed
+// from the start or middle fail with ErrAuditTampered.
+//
+// Removing entries from the end leaves a valid (shorter) chain, use
+// VerifyAuditLogHead with a Head you've kept elsewhere to catch that
+func VerifyAuditLog(r io.Reader) (*AuditEntry, error) {
+	return VerifyAuditLogHead(r, nil, 0, "")
+}
+
+// VerifyAuditLogFileHead is VerifyAuditLogHead for the file at path
+func VerifyAuditLogFileHead(path string, key []byte, seq uint64, hash string) (*AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return VerifyAuditLogHead(file, key, seq, hash)
+}
+
+// VerifyAuditLogHead is VerifyAuditLog for a log keyed with key (nil if
+// it isn't) that must reach the head seq and hash, as returned by
+// FileAuditLog.Head. A chain that stops short of it, or doesn't pass
+// through it, fails with ErrAuditTampered. Entries after the head are
+// fine as long as they chain on from it, a seq of 0 checks no head
+func VerifyAuditLogHead(r io.Reader, key []byte, seq uint64, hash string) (*AuditEntry, error) {
+	last, err := verifyAuditLog(r, key, seq, hash)
+	if err != nil {
+		return last, err
+	}
+
+	if seq > 0 && (last == nil || last.Seq < seq) {
+		lastSeq := uint64(0)
+		if last != nil {
+			lastSeq = last.Seq
+		}
+		return last, fmt.Errorf("%w: log ends at seq %d before the head at %d", ErrAuditTampered, lastSeq, seq)
+	}
+	return last, nil
+}
+
+func verifyAuditLog(r io.Reader, key []byte, headSeq uint64, headHash string) (*AuditEntry, error) {
+	var last *AuditEntry
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(b) == 0 {
+			return last, nil
+		}
+		if err != nil && err != io.EOF {
+			return last, err
+		}
+		if err == io.EOF {
+			return last, fmt.Errorf("%w: line %d is incomplete", ErrAuditTampered, line)
+		}
+
+		entry := &AuditEntry{}
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(entry); err != nil {
+			return last, fmt.Errorf("%w: line %d: %s", ErrAuditTampered, line, err)
+		}
+
+		expectSeq, expectPrev := uint64(1), ""
+		if last != nil {
+			expectSeq, expectPrev = last.Seq+1, last.Hash
+		}
+		if entry.Seq != expectSeq {
+			return last, fmt.Errorf("%w: line %d has seq %d, expected %d", ErrAuditTampered, line, entry.Seq, expectSeq)
+		}
+		if entry.PrevHash != expectPrev {
+			return last, fmt.Errorf("%w: line %d doesn't follow the entry before it", ErrAuditTampered, line)
+		}
+
+		hash, err := entry.hash(key)
+		if err != nil {
+			return last, err
+		}
+		if !hmac.Equal([]byte(hash), []byte(entry.Hash)) {
+			return last, fmt.Errorf("%w: line %d hash mismatch", ErrAuditTampered, line)
+		}
+		if entry.Seq == headSeq && entry.Hash != headHash {
+			return last, fmt.Errorf("%w: line %d isn't the head", ErrAuditTampered, line)
+		}
+
+		last = entry
+	}
+}
+
+// hash is the sha256 (HMAC-SHA256 with a key) of the entry's json with
+// the Hash left out, PrevHash is part of it which is what chains the
+// entries together
+func (e *AuditEntry) hash(key []byte) (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	b, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileAuditLog(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/ajaxop", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("cid") == "3" {
+			w.Write([]byte(`{"success":false,"message":"nope"}`))
+			return
+		}
+		w.Write([]byte(`{"success":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	account.AccountName = "bot"
+	account.steamClient().Audit = NewFileAuditLog(path)
+
+	account.AcceptConfirmation(&Confirmation{ConfirmationID: "1", Headline: "first"})
+	account.RejectConfirmation(&Confirmation{ConfirmationID: "2"})
+
+	// a fresh log picks the chain up where the file left off
+	account.steamClient().Audit = NewFileAuditLog(path)
+	account.AcceptConfirmation(&Confirmation{ConfirmationID: "3"})
+
+	last, err := VerifyAuditLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 3 || last.Target != "3" || last.Err == "" || last.SteamID != 76561198263585543 || last.Account != "bot" {
+		t.Errorf("last entry mismatched %#v", last)
+	}
+	if seq, hash := account.steamClient().Audit.(*FileAuditLog).Head(); seq != last.Seq || hash != last.Hash {
+		t.Errorf("head mismatched %d %s", seq, hash)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+
+	for name, tampered := range map[string][]byte{
+		"edited":    bytes.Replace(b, []byte(`"accept confirmation"`), []byte(`"reject confirmation"`), 1),
+		"removed":   append(append([]byte{}, lines[0]...), lines[2]...),
+		"reordered": append(append(append([]byte{}, lines[1]...), lines[0]...), lines[2]...),
+		"truncated": b[:len(b)-10],
+	} {
+		if _, err := VerifyAuditLog(bytes.NewReader(tampered)); !errors.Is(err, ErrAuditTampered) {
+			t.Errorf("expected %s log to fail with ErrAuditTampered, got %v", name, err)
+		}
+	}
+
+	ioutil.WriteFile(path, b[:len(b)-10], 0600)
+	if err := NewFileAuditLog(path).Record(&AuditEntry{Action: AuditAcceptConfirmation}); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected recording to a tampered log to fail, got %v", err)
+	}
+}
+
+func TestAuditLogHead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit key")
+	log := NewKeyedFileAuditLog(path, key)
+	for _, target := range []string{"1", "2", "3"} {
+		if err := log.Record(&AuditEntry{Action: AuditAcceptConfirmation, Target: target}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seq, hash := log.Head()
+
+	if last, err := VerifyAuditLogFileHead(path, key, seq, hash); err != nil || last.Seq != 3 {
+		t.Fatalf("expected the log to verify, got %v %v", last, err)
+	}
+	if _, err := VerifyAuditLogFile(path); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected a keyed log to fail without the key, got %v", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+
+	// cutting the last entry off leaves a valid chain that stops short of the head
+	if _, err := VerifyAuditLogHead(bytes.NewReader(bytes.Join(lines[:2], nil)), key, seq, hash); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected a truncated log to fail, got %v", err)
+	}
+	if _, err := VerifyAuditLogHead(bytes.NewReader(nil), key, seq, hash); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected an emptied log to fail, got %v", err)
+	}
+
+	// rewriting the log and chaining it back up without the key
+	rewritten := &bytes.Buffer{}
+	prev := ""
+	for i := range lines[:3] {
+		entry := &AuditEntry{Seq: uint64(i + 1), Action: AuditRejectConfirmation, PrevHash: prev}
+		entry.Hash, _ = entry.hash([]byte("guessed key"))
+		prev = entry.Hash
+		line, _ := json.Marshal(entry)
+		rewritten.Write(append(line, '\n'))
+	}
+	if _, err := VerifyAuditLogHead(bytes.NewReader(rewritten.Bytes()), key, seq, hash); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected a rewritten log to fail, got %v", err)
+	}
+
+	// entries after the head are fine
+	if err := log.Record(&AuditEntry{Action: AuditAcceptConfirmation, Target: "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := VerifyAuditLogFileHead(path, key, seq, hash); err != nil || last.Seq != 4 {
+		t.Errorf("expected the longer log to verify, got %v %v", last, err)
+	}
+	if _, err := VerifyAuditLogFileHead(path, key, seq, prev); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("expected the wrong head to fail, got %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// Hand rolled messages for the IAuthenticationService methods we use,
+// field numbers come from steammessages_auth.steamclient.proto
+
+// CAuthentication_GetPasswordRSAPublicKey_Request
+type authGetPasswordRSAPublicKeyRequest struct {
+	AccountName string
+}
+
+func (m *authGetPasswordRSAPublicKeyRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.string(1, m.AccountName)
+	return w
+}
+
+// CAuthentication_GetPasswordRSAPublicKey_Response
+type authGetPasswordRSAPublicKeyResponse struct {
+	PublicKeyMod string
+	PublicKeyExp string
+	Timestamp    uint64
+}
+
+func (m *authGetPasswordRSAPublicKeyResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.PublicKeyMod = f.string()
+		case 2:
+			m.PublicKeyExp = f.string()
+		case 3:
+			m.Timestamp = f.u
+		}
+		return nil
+	})
+}
+
+// CAuthentication_DeviceDetails
+type authDeviceDetails struct {
+	DeviceFriendlyName string
+	PlatformType       int
+	OSType             int32
+	GamingDeviceType   uint32
+}
+
+func (m *authDeviceDetails) marshalProto() []byte {
+	w := protoWriter{}
+	w.string(1, m.DeviceFriendlyName)
+	w.varint(2, uint64(m.PlatformType))
+	w.varint(3, uint64(m.OSType))
+	w.varint(4, uint64(m.GamingDeviceType))
+	return w
+}
+
+// CAuthentication_BeginAuthSessionViaCredentials_Request
+type authBeginAuthSessionViaCredentialsRequest struct {
+	AccountName         string
+	EncryptedPassword   string
+	EncryptionTimestamp uint64
+	RememberLogin       bool
+	Persistence         int
+	WebsiteID           string
+	DeviceDetails       *authDeviceDetails
+}
+
+func (m *authBeginAuthSessionViaCredentialsRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.string(2, m.AccountName)
+	w.string(3, m.EncryptedPassword)
+	w.varint(4, m.EncryptionTimestamp)
+	w.bool(5, m.RememberLogin)
+	w.varint(7, uint64(m.Persistence))
+	w.string(8, m.WebsiteID)
+	if m.DeviceDetails != nil {
+		w.message(9, m.DeviceDetails)
+	}
+	return w
+}
+
+// CAuthentication_AllowedConfirmation
+type authAllowedConfirmation struct {
+	ConfirmationType  int
+	AssociatedMessage string
+}
+
+func (m *authAllowedConfirmation) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ConfirmationType = int(f.u)
+		case 2:
+			m.AssociatedMessage = f.string()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_BeginAuthSessionViaCredentials_Response
+type authBeginAuthSessionViaCredentialsResponse struct {
+	ClientID             uint64
+	RequestID            []byte
+	Interval             float32
+	AllowedConfirmations []authAllowedConfirmation
+	SteamID              SteamID
+	WeakToken            string
+	ExtendedErrorMessage string
+}
+
+func (m *authBeginAuthSessionViaCredentialsResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ClientID = f.u
+		case 2:
+			m.RequestID = f.bytes()
+		case 3:
+			m.Interval = f.float32()
+		case 4:
+			confirmation := authAllowedConfirmation{}
+			if err := confirmation.unmarshalProto(f.b); err != nil {
+				return err
+			}
+			m.AllowedConfirmations = append(m.AllowedConfirmations, confirmation)
+		case 5:
+			m.SteamID = SteamID(f.u)
+		case 6:
+			m.WeakToken = f.string()
+		case 8:
+			m.ExtendedErrorMessage = f.string()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_BeginAuthSessionViaQR_Request
+type authBeginAuthSessionViaQRRequest struct {
+	DeviceDetails *authDeviceDetails
+	WebsiteID     string
+}
+
+func (m *authBeginAuthSessionViaQRRequest) marshalProto() []byte {
+	w := protoWriter{}
+	if m.DeviceDetails != nil {
+		w.message(3, m.DeviceDetails)
+	}
+	w.string(4, m.WebsiteID)
+	return w
+}
+
+// CAuthentication_BeginAuthSessionViaQR_Response
+type authBeginAuthSessionViaQRResponse struct {
+	ClientID             uint64
+	ChallengeURL         string
+	RequestID            []byte
+	Interval             float32
+	AllowedConfirmations []authAllowedConfirmation
+	Version              int32
+}
+
+func (m *authBeginAuthSessionViaQRResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ClientID = f.u
+		case 2:
+			m.ChallengeURL = f.string()
+		case 3:
+			m.RequestID = f.bytes()
+		case 4:
+			m.Interval = f.float32()
+		case 5:
+			confirmation := authAllowedConfirmation{}
+			if err := confirmation.unmarshalProto(f.b); err != nil {
+				return err
+			}
+			m.AllowedConfirmations = append(m.AllowedConfirmations, confirmation)
+		case 6:
+			m.Version = f.int32()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_PollAuthSessionStatus_Request
+type authPollAuthSessionStatusRequest struct {
+	ClientID  uint64
+	RequestID []byte
+}
+
+func (m *authPollAuthSessionStatusRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.varint(1, m.ClientID)
+	w.bytes(2, m.RequestID)
+	return w
+}
+
+// CAuthentication_PollAuthSessionStatus_Response
+type authPollAuthSessionStatusResponse struct {
+	NewClientID          uint64
+	NewChallengeURL      string
+	RefreshToken         string
+	AccessToken          string
+	HadRemoteInteraction bool
+	AccountName          string
+}
+
+func (m *authPollAuthSessionStatusResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.NewClientID = f.u
+		case 2:
+			m.NewChallengeURL = f.string()
+		case 3:
+			m.RefreshToken = f.string()
+		case 4:
+			m.AccessToken = f.string()
+		case 5:
+			m.HadRemoteInteraction = f.bool()
+		case 6:
+			m.AccountName = f.string()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request
+type authUpdateAuthSessionWithSteamGuardCodeRequest struct {
+	ClientID uint64
+	SteamID  SteamID
+	Code     string
+	CodeType int
+}
+
+func (m *authUpdateAuthSessionWithSteamGuardCodeRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.varint(1, m.ClientID)
+	w.fixed64(2, uint64(m.SteamID))
+	w.string(3, m.Code)
+	w.varint(4, uint64(m.CodeType))
+	return w
+}
+
+// CAuthentication_AccessToken_GenerateForApp_Request
+type authGenerateAccessTokenForAppRequest struct {
+	RefreshToken string
+	SteamID      SteamID
+	RenewalType  int
+}
+
+func (m *authGenerateAccessTokenForAppRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.string(1, m.RefreshToken)
+	w.fixed64(2, uint64(m.SteamID))
+	w.varint(3, uint64(m.RenewalType))
+	return w
+}
+
+// CAuthentication_AccessToken_GenerateForApp_Response
+type authGenerateAccessTokenForAppResponse struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+func (m *authGenerateAccessTokenForAppResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.AccessToken = f.string()
+		case 2:
+			m.RefreshToken = f.string()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_GetAuthSessionsForAccount_Response, the request is empty
+type authGetAuthSessionsForAccountResponse struct {
+	ClientIDs []uint64
+}
+
+func (m *authGetAuthSessionsForAccountResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		// repeated scalars may or may not arrive packed
+		if f.typ != protowire.BytesType {
+			m.ClientIDs = append(m.ClientIDs, f.u)
+			return nil
+		}
+		for packed := f.b; len(packed) > 0; {
+			v, n := protowire.ConsumeVarint(packed)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.ClientIDs = append(m.ClientIDs, v)
+			packed = packed[n:]
+		}
+		return nil
+	})
+}
+
+// CAuthentication_GetAuthSessionInfo_Request
+type authGetAuthSessionInfoRequest struct {
+	ClientID uint64
+}
+
+func (m *authGetAuthSessionInfoRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.varint(1, m.ClientID)
+	return w
+}
+
+// CAuthentication_GetAuthSessionInfo_Response
+func (m *AuthSessionInfo) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.IP = f.string()
+		case 2:
+			m.Geoloc = f.string()
+		case 3:
+			m.City = f.string()
+		case 4:
+			m.State = f.string()
+		case 5:
+			m.Country = f.string()
+		case 6:
+			m.PlatformType = int(f.u)
+		case 7:
+			m.DeviceFriendlyName = f.string()
+		case 8:
+			m.Version = f.int32()
+		case 10:
+			m.RequestorLocationMismatch = f.bool()
+		case 11:
+			m.HighUsageLogin = f.bool()
+		}
+		return nil
+	})
+}
+
+// CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request
+type authUpdateAuthSessionWithMobileConfirmationRequest struct {
+	Version     int32
+	ClientID    uint64
+	SteamID     SteamID
+	Signature   []byte
+	Confirm     bool
+	Persistence int
+}
+
+func (m *authUpdateAuthSessionWithMobileConfirmationRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.varint(1, uint64(m.Version))
+	w.varint(2, m.ClientID)
+	w.fixed64(3, uint64(m.SteamID))
+	w.bytes(4, m.Signature)
+	w.bool(5, m.Confirm)
+	w.varint(6, uint64(m.Persistence))
+	return w
+}
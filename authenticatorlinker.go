@@ -5,12 +5,12 @@
 package steamauth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha1"
 	"encoding/hex"
 	"net/http/cookiejar"
 	"net/url"
-	"strconv"
 )
 
 // AuthenticatorLinker will link this Authenticator to your steam account
@@ -21,25 +21,22 @@ type AuthenticatorLinker struct {
 	DeviceID      string
 	LinkedAccount SteamGuardAccount
 	Finalized     bool
+	// Store, if set, gets LinkedAccount as soon as steam hands over the
+	// secrets and again once it's finalized
+	Store AccountStore
 
+	client    *Client
 	session   *SessionData
 	cookieJar *cookiejar.Jar
 }
 
 // NewAuthenticatorLinker will create an account linker
+// bound to the DefaultClient
 //
 // Pass it the SessionData from a logged in instance of
 // a UserLogin structure.
 func NewAuthenticatorLinker(session *SessionData) *AuthenticatorLinker {
-	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
-
-	session.SetCookies(cookieJar)
-
-	return &AuthenticatorLinker{
-		session:   session,
-		DeviceID:  generateDeviceID(),
-		cookieJar: cookieJar,
-	}
+	return DefaultClient.NewAuthenticatorLinker(session)
 }
 
 // AddAuthenticator will configure your account for steamguard,
@@ -50,56 +47,80 @@ func NewAuthenticatorLinker(session *SessionData) *AuthenticatorLinker {
 // sent to the linked phone and submit it with
 // `FinalizeAddAuthenticator`
 func (al *AuthenticatorLinker) AddAuthenticator() (LinkResult, error) {
-	hasPhone := al.hasPhoneAttached()
+	return al.AddAuthenticatorContext(context.Background())
+}
+
+// AddAuthenticatorContext is AddAuthenticator with a context that
+// can cancel any in-flight requests to steam
+func (al *AuthenticatorLinker) AddAuthenticatorContext(ctx context.Context) (LinkResult, error) {
+	hasPhone, err := al.hasPhoneAttached(ctx)
+	if err != nil {
+		al.client.logf("Protocol error: %s", err)
+		return LinkGeneralFailure, err
+	}
 	if hasPhone && al.PhoneNumber != "" {
-		log(MustRemovePhoneNumber)
+		al.client.log(MustRemovePhoneNumber)
 		return MustRemovePhoneNumber, nil
 	}
 	if !hasPhone && al.PhoneNumber == "" {
-		log(MustProvidePhoneNumber)
+		al.client.log(MustProvidePhoneNumber)
 		return MustProvidePhoneNumber, nil
 	}
 
 	if !hasPhone {
-		if !al.addPhoneNumber() {
-			log(LinkGeneralFailure)
-			return LinkGeneralFailure, nil
+		if err := al.addPhoneNumber(ctx); err != nil {
+			al.client.log(LinkGeneralFailure)
+			return LinkGeneralFailure, err
 		}
 	}
 
-	var postData = url.Values{
-		"access_token":       []string{al.session.OAuthToken},
-		"steamid":            []string{al.session.SteamID.String()},
-		"authenticator_type": []string{"1"},
-		"device_identifier":  []string{al.DeviceID},
-		"sms_phone_id":       []string{"1"},
+	steamTime, err := al.client.TimeAligner().GetSteamTimeContext(ctx)
+	if err != nil {
+		return LinkGeneralFailure, err
+	}
+
+	addRequest := twoFactorAddAuthenticatorRequest{
+		SteamID:           al.session.SteamID,
+		AuthenticatorTime: uint64(steamTime.Unix()),
+		AuthenticatorType: 1,
+		DeviceIdentifier:  al.DeviceID,
+		SMSPhoneID:        "1",
 	}
 
-	logf("Attempting add authenticator for device %s", al.DeviceID)
+	al.client.logf("Attempting add authenticator for device %s", al.DeviceID)
 
-	addAuthenticatorResponse := AddAuthenticatorResponse{}
-	_, err := SteamWeb().
-		SetParams(postData).
-		Post(APIEndpoints.SteamAPIBase.String() + "/ITwoFactorService/AddAuthenticator/v0001").
-		HandleJSON(&addAuthenticatorResponse).
-		MobileLoginRequest()
+	addAuthenticatorResponse := twoFactorAddAuthenticatorResponse{}
+	_, err = al.client.SteamWeb().
+		SetContext(ctx).
+		SetAccessToken(al.session.accessToken()).
+		Post(al.client.Endpoints.SteamAPIBase.String() + "/ITwoFactorService/AddAuthenticator/v1").
+		SetProtobuf(&addRequest).
+		HandleProtobuf(&addAuthenticatorResponse).
+		Do()
 
 	if err != nil {
-		logf("Protocol error: %s", err)
+		al.client.logf("Protocol error: %s", err)
 		return LinkGeneralFailure, err
 	}
 
-	if addAuthenticatorResponse.Response.Status != 1 {
-		logf("Protocol error: Response.Status was %d, expected 1", addAuthenticatorResponse.Response.Status)
-		return LinkGeneralFailure, nil
+	if addAuthenticatorResponse.Status != EResultOK {
+		al.client.logf("Protocol error: Response.Status was %s, expected %s", addAuthenticatorResponse.Status, EResultOK)
+		return LinkGeneralFailure, &SteamError{Op: "add authenticator", EResult: addAuthenticatorResponse.Status}
 	}
 
 	// SteamGuardAccount?
-	al.LinkedAccount = addAuthenticatorResponse.Response
+	al.LinkedAccount = addAuthenticatorResponse.account()
 	al.LinkedAccount.Session = al.session
+	al.LinkedAccount.client = al.client
 	al.LinkedAccount.DeviceID = al.DeviceID
 
-	log(AwaitingFinalization)
+	// the revocation code is the only way back if finalizing goes wrong
+	if err := al.store(); err != nil {
+		al.client.logf("Unable to store linked account: %s", err)
+		return LinkGeneralFailure, err
+	}
+
+	al.client.log(AwaitingFinalization)
 	return AwaitingFinalization, nil
 }
 
@@ -107,100 +128,134 @@ func (al *AuthenticatorLinker) AddAuthenticator() (LinkResult, error) {
 // once you've sucessfully called this method you need
 // to save the instance of `SteamGuardAccount` stored in
 // `LinkedAccount` or you risk losing access to your
-// steam account
+// steam account. With a Store that fails to save it you get
+// Success along with the error, the authenticator is active
 func (al *AuthenticatorLinker) FinalizeAddAuthenticator(smsCode string) (FinalizeResult, error) {
-	smsCodeGood := false
+	return al.FinalizeAddAuthenticatorContext(context.Background(), smsCode)
+}
 
-	var postData = url.Values{
-		"steamid":            []string{al.session.SteamID.String()},
-		"access_token":       []string{al.session.OAuthToken},
-		"authenticator_code": []string{""},
-		"activation_code":    []string{smsCode},
+// FinalizeAddAuthenticatorContext is FinalizeAddAuthenticator with a
+// context that can cancel any in-flight requests to steam
+func (al *AuthenticatorLinker) FinalizeAddAuthenticatorContext(ctx context.Context, smsCode string) (FinalizeResult, error) {
+	finalizeRequest := twoFactorFinalizeAddAuthenticatorRequest{
+		SteamID:         al.session.SteamID,
+		ActivationCode:  smsCode,
+		ValidateSMSCode: true,
 	}
 
 	for tries := 0; tries <= 30; {
-		postData.Set("authenticator_code", iif(tries == 0, "", al.LinkedAccount.GenerateSteamGuardCode()))
-		postData.Set("authenticator_time", strconv.FormatInt(TimeAligner.GetSteamTime().Unix(), 10))
-
-		if smsCodeGood {
-			postData.Set("activation_code", "")
-		}
-
-		logf("Attempting finalize authentication, attempt %d of 30", tries+1)
-
-		finalizeResponse := FinalizeAuthenticatorResponse{}
-		_, err := SteamWeb().
-			Post(APIEndpoints.SteamAPIBase.String() + "/ITwoFactorService/FinalizeAddAuthenticator/v0001").
-			SetParams(postData).
-			HandleJSON(&finalizeResponse).
-			MobileLoginRequest()
-
+		steamTime, err := al.client.TimeAligner().GetSteamTimeContext(ctx)
 		if err != nil {
-			logf("Protocol error: %s", err)
+			return FinalizeGeneralFailure, err
+		}
+		code := ""
+		if tries > 0 {
+			if code, err = al.LinkedAccount.GenerateSteamGuardCodeForTime(steamTime); err != nil {
+				return FinalizeGeneralFailure, err
+			}
+		}
+		finalizeRequest.AuthenticatorCode = code
+		finalizeRequest.AuthenticatorTime = uint64(steamTime.Unix())
+
+		al.client.logf("Attempting finalize authentication, attempt %d of 30", tries+1)
+
+		finalizeResponse := twoFactorFinalizeAddAuthenticatorResponse{}
+		_, err = al.client.SteamWeb().
+			SetContext(ctx).
+			SetAccessToken(al.session.accessToken()).
+			Post(al.client.Endpoints.SteamAPIBase.String() + "/ITwoFactorService/FinalizeAddAuthenticator/v1").
+			SetProtobuf(&finalizeRequest).
+			HandleProtobuf(&finalizeResponse).
+			Do()
+
+		if isEResult(err, EResultTwoFactorActivationCodeMismatch) {
+			finalizeResponse.Status = EResultTwoFactorActivationCodeMismatch
+		} else if err != nil {
+			al.client.logf("Protocol error: %s", err)
 			return FinalizeGeneralFailure, err
 		}
 
-		if finalizeResponse.Response.Status == 89 {
-			log(BadSMSCode)
+		if finalizeResponse.Status == EResultTwoFactorActivationCodeMismatch {
+			al.client.log(BadSMSCode)
 			return BadSMSCode, nil
 		}
 
-		if finalizeResponse.Response.Status == 88 && tries >= 30 {
-			log(UnableToGenerateCorrectCodes)
+		if finalizeResponse.Status == EResultTwoFactorCodeMismatch && tries >= 30 {
+			al.client.log(UnableToGenerateCorrectCodes)
 			return UnableToGenerateCorrectCodes, nil
 		}
 
-		if !finalizeResponse.Response.Success {
-			log("Protocol error: Response.Success == false")
-			return FinalizeGeneralFailure, nil
+		if !finalizeResponse.Success {
+			al.client.log("Protocol error: Response.Success == false")
+			return FinalizeGeneralFailure, &SteamError{Op: "finalize authenticator", EResult: finalizeResponse.Status}
 		}
 
-		if finalizeResponse.Response.WantMore {
-			log("Steam wants more")
-			smsCodeGood = true
+		if finalizeResponse.WantMore {
+			al.client.log("Steam wants more")
+			finalizeRequest.ActivationCode = ""
+			finalizeRequest.ValidateSMSCode = false
 			tries++
 			continue
 		}
 
 		al.LinkedAccount.FullyEnrolled = true
-		log(Success)
+		al.client.audit(&al.LinkedAccount, AuditFinalizeAuthenticator, al.LinkedAccount.SerialNumber, al.DeviceID, nil)
+		al.client.notify(&al.LinkedAccount, NotifyAuthenticatorAdded, nil)
+		if err := al.store(); err != nil {
+			al.client.logf("Unable to store linked account: %s", err)
+			return Success, err
+		}
+		al.client.log(Success)
 		return Success, nil
 	}
 
 	return FinalizeGeneralFailure, nil
 }
 
-func (al *AuthenticatorLinker) addPhoneNumber() bool {
-	logf("Add phone number %s", al.PhoneNumber)
+func (al *AuthenticatorLinker) store() error {
+	if al.Store == nil {
+		return nil
+	}
+	return al.Store.Put(&al.LinkedAccount)
+}
+
+func (al *AuthenticatorLinker) addPhoneNumber(ctx context.Context) error {
+	al.client.logf("Add phone number %s", al.PhoneNumber)
 	addPhoneResponse := AddPhoneResponse{}
-	_, err := SteamWeb().
+	_, err := al.client.SteamWeb().
+		SetContext(ctx).
 		SetJar(al.cookieJar).
-		Get(APIEndpoints.CommunityBase.String() + "/steamguard/phoneajax?op=add_phone_number&arg=" + url.QueryEscape(al.PhoneNumber)).
+		Get(al.client.Endpoints.CommunityBase.String() + "/steamguard/phoneajax?op=add_phone_number&arg=" + url.QueryEscape(al.PhoneNumber)).
 		HandleJSON(&addPhoneResponse).
 		Do()
 
 	if err != nil {
-		log("Internal Error: ", err)
-		return false
+		al.client.log("Internal Error: ", err)
+		return err
 	}
 
-	return addPhoneResponse.Success
+	if !addPhoneResponse.Success {
+		return &SteamError{Op: "add phone number"}
+	}
+
+	return nil
 }
 
-func (al *AuthenticatorLinker) hasPhoneAttached() bool {
-	logf("has phone attached?")
+func (al *AuthenticatorLinker) hasPhoneAttached(ctx context.Context) (bool, error) {
+	al.client.logf("has phone attached?")
 	hasPhoneResponse := HasPhoneResponse{}
-	_, err := SteamWeb().
+	_, err := al.client.SteamWeb().
+		SetContext(ctx).
 		SetJar(al.cookieJar).
-		Get(APIEndpoints.CommunityBase.String() + "/steamguard/phoneajax?op=has_phone&arg=").
-		HandleJSON(hasPhoneResponse).
+		Get(al.client.Endpoints.CommunityBase.String() + "/steamguard/phoneajax?op=has_phone&arg=").
+		HandleJSON(&hasPhoneResponse).
 		MobileLoginRequest()
 
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	return hasPhoneResponse.HasPhone
+	return hasPhoneResponse.HasPhone, nil
 }
 
 func generateDeviceID() string {
@@ -244,21 +299,31 @@ const (
 var finalizeResults = []string{
 	BadSMSCode:                   "bad sms code",
 	UnableToGenerateCorrectCodes: "unable to generate correct codes",
-	Success:                "success",
-	FinalizeGeneralFailure: "general failure",
+	Success:                      "success",
+	FinalizeGeneralFailure:       "general failure",
 }
 
 func (f FinalizeResult) String() string {
 	return finalizeResults[f]
 }
 
+// AddAuthenticatorResponse is the json response AddAuthenticator used
+// to get from steam.
+//
+// Deprecated: AddAuthenticator speaks protobuf now, this is kept so
+// code that refers to it still builds
 type AddAuthenticatorResponse struct {
 	Response SteamGuardAccount `json:"response"`
 }
 
+// FinalizeAuthenticatorResponse is the json response FinalizeAddAuthenticator
+// used to get from steam.
+//
+// Deprecated: FinalizeAddAuthenticator speaks protobuf now, this is kept
+// so code that refers to it still builds
 type FinalizeAuthenticatorResponse struct {
 	Response struct {
-		Status     int       `json:"status"`
+		Status     EResult   `json:"status"`
 		ServerTime timestamp `json:"server_time"`
 		WantMore   bool      `json:"want_more"`
 		Success    bool      `json:"success"`
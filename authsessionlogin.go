@@ -0,0 +1,337 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// AuthSessionLogin lets you log in to steam as a user via the
+// IAuthenticationService, this is the flow steam uses today and
+// yields an access and refresh token.
+//
+// It works much like UserLogin, keep calling DoLogin() until you
+// get LoginOkay, filling in TwoFactorCode or EmailCode as asked.
+// If SteamGuard is set the two factor code is generated and
+// submitted for you.
+type AuthSessionLogin struct {
+	Username string
+	Password string
+	SteamID  SteamID
+
+	RequiresEmail bool
+	EmailDomain   string
+	EmailCode     string
+
+	Requires2FA   bool
+	TwoFactorCode string
+
+	SteamGuard *SteamGuardAccount
+
+	AccessToken  string
+	RefreshToken string
+
+	Session  *SessionData
+	LoggedIn bool
+
+	client    *Client
+	clientID  uint64
+	requestID []byte
+	interval  time.Duration
+}
+
+// NewAuthSessionLogin allocates and returns a new AuthSessionLogin
+// bound to the DefaultClient.
+func NewAuthSessionLogin(username, password string) *AuthSessionLogin {
+	return DefaultClient.NewAuthSessionLogin(username, password)
+}
+
+// DoLogin actually attempt to login.
+// Grabs the RSA public key.
+// Encrypts your password.
+// Begins an auth session.
+// Submits any steam guard code.
+// Polls until steam hands over the tokens.
+func (a *AuthSessionLogin) DoLogin() (LoginResult, error) {
+	return a.DoLoginContext(context.Background())
+}
+
+// DoLoginContext is DoLogin with a context that can cancel
+// any in-flight requests to steam
+func (a *AuthSessionLogin) DoLoginContext(ctx context.Context) (LoginResult, error) {
+	c := a.client
+
+	if a.clientID == 0 {
+		if res, err := a.beginAuthSession(ctx); err != nil {
+			return res, err
+		}
+	}
+
+	if a.Requires2FA {
+		code := a.TwoFactorCode
+		if a.SteamGuard != nil {
+			steamTime, err := c.TimeAligner().GetSteamTimeContext(ctx)
+			if err != nil {
+				return LoginGeneralFailure, err
+			}
+			if code, err = a.SteamGuard.GenerateSteamGuardCodeForTime(steamTime); err != nil {
+				return LoginGeneralFailure, err
+			}
+		}
+
+		if code == "" {
+			c.log(Need2FA)
+			return Need2FA, nil
+		}
+
+		if err := a.updateWithSteamGuardCode(ctx, code, authSessionGuardDeviceCode); err != nil {
+			if isEResult(err, EResultTwoFactorCodeMismatch) && a.SteamGuard == nil {
+				c.log(Need2FA)
+				a.TwoFactorCode = ""
+				return Need2FA, nil
+			}
+			return LoginGeneralFailure, err
+		}
+		a.Requires2FA = false
+	}
+
+	if a.RequiresEmail {
+		if a.EmailCode == "" {
+			c.log(NeedEmail)
+			return NeedEmail, nil
+		}
+
+		if err := a.updateWithSteamGuardCode(ctx, a.EmailCode, authSessionGuardEmailCode); err != nil {
+			if isEResult(err, EResultInvalidLoginAuthCode) {
+				c.log(NeedEmail)
+				a.EmailCode = ""
+				return NeedEmail, nil
+			}
+			return LoginGeneralFailure, err
+		}
+		a.RequiresEmail = false
+	}
+
+	return a.pollAuthSession(ctx)
+}
+
+func (a *AuthSessionLogin) beginAuthSession(ctx context.Context) (LoginResult, error) {
+	c := a.client
+
+	c.logf("Retriving RSAKey for %s", a.Username)
+	rsaResponse := authGetPasswordRSAPublicKeyResponse{}
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		Get(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/GetPasswordRSAPublicKey/v1").
+		SetProtobuf(&authGetPasswordRSAPublicKeyRequest{AccountName: a.Username}).
+		HandleProtobuf(&rsaResponse).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error %s", err)
+		return LoginGeneralFailure, err
+	}
+
+	publicKey, err := parseRSAPublicKey(rsaResponse.PublicKeyMod, rsaResponse.PublicKeyExp)
+	if err != nil {
+		return BadRSA, err
+	}
+
+	encryptedPassword, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(a.Password))
+	if err != nil {
+		return BadRSA, err
+	}
+
+	beginRequest := authBeginAuthSessionViaCredentialsRequest{
+		AccountName:         a.Username,
+		EncryptedPassword:   base64.StdEncoding.EncodeToString(encryptedPassword),
+		EncryptionTimestamp: rsaResponse.Timestamp,
+		RememberLogin:       true,
+		Persistence:         sessionPersistent,
+		WebsiteID:           "Mobile",
+		DeviceDetails:       mobileDeviceDetails(),
+	}
+
+	c.logf("Attempting to authenticate as %s", a.Username)
+	beginResponse := authBeginAuthSessionViaCredentialsResponse{}
+	_, err = c.SteamWeb().
+		SetContext(ctx).
+		Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/BeginAuthSessionViaCredentials/v1").
+		SetProtobuf(&beginRequest).
+		HandleProtobuf(&beginResponse).
+		Do()
+
+	if err != nil {
+		if isEResult(err, EResultInvalidPassword) {
+			c.log(BadCredentials)
+			return BadCredentials, err
+		}
+		c.logf("Protocol error %s", err)
+		return LoginGeneralFailure, err
+	}
+
+	response := beginResponse
+	if response.ClientID == 0 {
+		c.logf("Protocol error: missing client id")
+		return LoginGeneralFailure, errors.New("missing client id")
+	}
+
+	a.clientID = response.ClientID
+	a.requestID = response.RequestID
+	a.SteamID = response.SteamID
+	a.interval = pollInterval(response.Interval)
+
+	for _, confirmation := range response.AllowedConfirmations {
+		switch confirmation.ConfirmationType {
+		case authSessionGuardDeviceCode:
+			a.Requires2FA = true
+		case authSessionGuardEmailCode:
+			a.RequiresEmail = true
+			a.EmailDomain = confirmation.AssociatedMessage
+		}
+	}
+
+	// Prefer the authenticator over email when steam allows both
+	if a.Requires2FA {
+		a.RequiresEmail = false
+	}
+
+	return LoginOkay, nil
+}
+
+func (a *AuthSessionLogin) updateWithSteamGuardCode(ctx context.Context, code string, codeType int) error {
+	c := a.client
+	updateRequest := authUpdateAuthSessionWithSteamGuardCodeRequest{
+		ClientID: a.clientID,
+		SteamID:  a.SteamID,
+		Code:     code,
+		CodeType: codeType,
+	}
+
+	c.logf("Submitting steam guard code for %s", a.Username)
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1").
+		SetProtobuf(&updateRequest).
+		HandleProtobuf(&protoEmpty{}).
+		Do()
+
+	return err
+}
+
+func (a *AuthSessionLogin) pollAuthSession(ctx context.Context) (LoginResult, error) {
+	c := a.client
+	pollRequest := authPollAuthSessionStatusRequest{
+		ClientID:  a.clientID,
+		RequestID: a.requestID,
+	}
+
+	for {
+		c.logf("Polling auth session for %s", a.Username)
+		pollResponse := authPollAuthSessionStatusResponse{}
+		_, err := c.SteamWeb().
+			SetContext(ctx).
+			Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/PollAuthSessionStatus/v1").
+			SetProtobuf(&pollRequest).
+			HandleProtobuf(&pollResponse).
+			Do()
+
+		if err != nil {
+			c.logf("Protocol error %s", err)
+			a.clientID = 0
+			return LoginGeneralFailure, err
+		}
+
+		if pollResponse.RefreshToken != "" {
+			a.finishLogin(pollResponse.AccessToken, pollResponse.RefreshToken)
+			c.log(LoginOkay)
+			return LoginOkay, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return LoginGeneralFailure, ctx.Err()
+		case <-time.After(a.interval):
+		}
+	}
+}
+
+func (a *AuthSessionLogin) finishLogin(accessToken, refreshToken string) {
+	a.AccessToken = accessToken
+	a.RefreshToken = refreshToken
+	a.clientID = 0
+	a.requestID = nil
+	a.LoggedIn = true
+
+	a.Session = &SessionData{
+		SteamID:      a.SteamID,
+		RefreshToken: refreshToken,
+		SessionID:    generateSessionID(),
+	}
+	a.Session.setAccessToken(accessToken)
+}
+
+func generateSessionID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Steam says how often to poll an auth session, these keep us from
+// hammering it when that's missing or silly
+var (
+	minPollInterval     = time.Second
+	defaultPollInterval = 5 * time.Second
+)
+
+// pollInterval is the interval steam asked for in seconds, clamped
+func pollInterval(seconds float32) time.Duration {
+	if !(seconds > 0) {
+		return defaultPollInterval
+	}
+	interval := time.Duration(float64(seconds) * float64(time.Second))
+	if interval < minPollInterval {
+		return minPollInterval
+	}
+	return interval
+}
+
+// EAuthSessionGuardType values steam uses in allowed_confirmations
+const (
+	authSessionGuardUnknown            = 0
+	authSessionGuardNone               = 1
+	authSessionGuardEmailCode          = 2
+	authSessionGuardDeviceCode         = 3
+	authSessionGuardDeviceConfirmation = 4
+	authSessionGuardEmailConfirmation  = 5
+	authSessionGuardMachineToken       = 6
+)
+
+// EAuthTokenPlatformType we claim to be
+const authTokenPlatformMobileApp = 3
+
+// ETokenRenewalType, allow lets steam hand back a new refresh token
+const tokenRenewalAllow = 1
+
+// ESessionPersistence we ask for
+const sessionPersistent = 1
+
+// mobileDeviceDetails describes us to steam as the mobile app
+func mobileDeviceDetails() *authDeviceDetails {
+	return &authDeviceDetails{
+		DeviceFriendlyName: "go-steamauth",
+		PlatformType:       authTokenPlatformMobileApp,
+		OSType:             -500, // k_EOSTypeAndroidUnknown
+		GamingDeviceType:   528,  // k_EGamingDeviceType_Phone
+	}
+}
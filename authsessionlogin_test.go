@@ -0,0 +1,171 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fastPolling lets tests poll quicker than steam can ask us to
+func fastPolling(t *testing.T, min, def time.Duration) {
+	oldMin, oldDefault := minPollInterval, defaultPollInterval
+	minPollInterval, defaultPollInterval = min, def
+	t.Cleanup(func() { minPollInterval, defaultPollInterval = oldMin, oldDefault })
+}
+
+func TestPollInterval(t *testing.T) {
+	for _, test := range []struct {
+		seconds  float32
+		expected time.Duration
+	}{
+		{0, 5 * time.Second},
+		{-1, 5 * time.Second},
+		{float32(math.NaN()), 5 * time.Second},
+		{0.01, time.Second},
+		{2.5, 2500 * time.Millisecond},
+	} {
+		if interval := pollInterval(test.seconds); interval != test.expected {
+			t.Errorf("expected %v to poll every %s, got %s", test.seconds, test.expected, interval)
+		}
+	}
+}
+
+func TestAuthSessionPollZeroInterval(t *testing.T) {
+	fastPolling(t, 10*time.Millisecond, 100*time.Millisecond)
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+	login := client.NewAuthSessionLogin("test", "hunter2")
+	login.clientID = 42
+	login.interval = pollInterval(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+	if _, err := login.pollAuthSession(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the poll to time out, got %v", err)
+	}
+	if polls > 5 {
+		t.Errorf("expected a missing interval to poll at the default, polled %d times", polls)
+	}
+}
+
+func TestAuthSessionLoginWithSteamGuard(t *testing.T) {
+	fastPolling(t, time.Millisecond, time.Millisecond)
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := &SteamGuardAccount{SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="}
+	client := NewClient()
+	submittedCode := ""
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/GetPasswordRSAPublicKey/v1", func(w http.ResponseWriter, r *http.Request) {
+		response := protoWriter{}
+		response.string(1, fmt.Sprintf("%x", key.N))
+		response.string(2, fmt.Sprintf("%x", key.E))
+		response.varint(3, 1234)
+		w.Write(response)
+	})
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaCredentials/v1", func(w http.ResponseWriter, r *http.Request) {
+		var encrypted []byte
+		var timestamp uint64
+		rangeProtoFields(protoRequest(r), func(f protoField) error {
+			switch f.num {
+			case 3:
+				encrypted, _ = base64.StdEncoding.DecodeString(f.string())
+			case 4:
+				timestamp = f.u
+			}
+			return nil
+		})
+		password, err := rsa.DecryptPKCS1v15(rand.Reader, key, encrypted)
+		if err != nil || string(password) != "hunter2" || timestamp != 1234 {
+			w.Header().Set("X-eresult", "5")
+			return
+		}
+
+		confirmation := protoWriter{}
+		confirmation.varint(1, authSessionGuardDeviceCode)
+		response := protoWriter{}
+		response.varint(1, 42)
+		response.bytes(2, []byte{1, 2, 3})
+		response = protowire.AppendTag(response, 3, protowire.Fixed32Type)
+		response = protowire.AppendFixed32(response, math.Float32bits(0.01))
+		response.bytes(4, confirmation)
+		response.varint(5, 76561198263585543)
+		w.Write(response)
+	})
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1", func(w http.ResponseWriter, r *http.Request) {
+		rangeProtoFields(protoRequest(r), func(f protoField) error {
+			if f.num == 3 {
+				submittedCode = f.string()
+			}
+			return nil
+		})
+	})
+	polls := 0
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		if polls++; polls < 2 {
+			return
+		}
+		response := protoWriter{}
+		response.string(3, "refresh")
+		response.string(4, "access")
+		response.string(6, "test")
+		w.Write(response)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+	client.TimeAligner().aligned = true
+
+	login := client.NewAuthSessionLogin("test", "hunter2")
+	login.SteamGuard = account
+
+	res, err := login.DoLogin()
+	if err != nil || res != LoginOkay {
+		t.Fatalf("expected login okay, got %s %v", res, err)
+	}
+
+	expectedCode, _ := account.GenerateSteamGuardCodeForTime(time.Now())
+	previousCode, _ := account.GenerateSteamGuardCodeForTime(time.Now().Add(-30 * time.Second))
+	if submittedCode != expectedCode && submittedCode != previousCode {
+		t.Errorf("submitted code mismatched `%s` <> `%s`", submittedCode, expectedCode)
+	}
+
+	if login.RefreshToken != "refresh" || login.AccessToken != "access" {
+		t.Error("tokens were not captured")
+	}
+
+	if login.Session.SteamID != SteamID(76561198263585543) || login.Session.SteamLoginSecure != "76561198263585543%7C%7Caccess" {
+		t.Errorf("session mismatched %#v", login.Session)
+	}
+
+	bad := client.NewAuthSessionLogin("test", "wrong")
+	if res, err := bad.DoLogin(); res != BadCredentials || !isEResult(err, EResultInvalidPassword) {
+		t.Errorf("expected bad credentials, got %s %v", res, err)
+	}
+}
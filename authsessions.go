@@ -0,0 +1,177 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AuthSessionInfo describes a pending sign in (a QR code scanned on a
+// desktop, or a "is this you?" prompt) waiting on the authenticator
+type AuthSessionInfo struct {
+	ClientID                  uint64
+	IP                        string
+	Geoloc                    string
+	City                      string
+	State                     string
+	Country                   string
+	PlatformType              int
+	DeviceFriendlyName        string
+	Version                   int32
+	RequestorLocationMismatch bool
+	HighUsageLogin            bool
+}
+
+// FetchAuthSessions that are waiting on this authenticator to approve them
+func (s *SteamGuardAccount) FetchAuthSessions() ([]*AuthSessionInfo, error) {
+	return s.FetchAuthSessionsContext(context.Background())
+}
+
+// FetchAuthSessionsContext is FetchAuthSessions with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) FetchAuthSessionsContext(ctx context.Context) ([]*AuthSessionInfo, error) {
+	if s.Session == nil {
+		return nil, ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return nil, err
+	}
+
+	c := s.steamClient()
+	c.log("Fetching pending auth sessions")
+	sessionsResponse := authGetAuthSessionsForAccountResponse{}
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		SetAccessToken(s.Session.accessToken()).
+		Get(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/GetAuthSessionsForAccount/v1").
+		SetProtobuf(&protoEmpty{}).
+		HandleProtobuf(&sessionsResponse).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error: %s", err)
+		return nil, err
+	}
+
+	ret := make([]*AuthSessionInfo, 0, len(sessionsResponse.ClientIDs))
+	for _, clientID := range sessionsResponse.ClientIDs {
+		info := &AuthSessionInfo{}
+		_, err := c.SteamWeb().
+			SetContext(ctx).
+			SetAccessToken(s.Session.accessToken()).
+			Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/GetAuthSessionInfo/v1").
+			SetProtobuf(&authGetAuthSessionInfoRequest{ClientID: clientID}).
+			HandleProtobuf(info).
+			Do()
+
+		if err != nil {
+			c.logf("Protocol error: %s", err)
+			return nil, err
+		}
+
+		info.ClientID = clientID
+		ret = append(ret, info)
+	}
+
+	return ret, nil
+}
+
+// ApproveAuthSession lets the pending sign in through
+func (s *SteamGuardAccount) ApproveAuthSession(info *AuthSessionInfo) error {
+	return s.ApproveAuthSessionContext(context.Background(), info)
+}
+
+// ApproveAuthSessionContext is ApproveAuthSession with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) ApproveAuthSessionContext(ctx context.Context, info *AuthSessionInfo) error {
+	return s.updateAuthSession(ctx, info, true)
+}
+
+// DenyAuthSession turns the pending sign in away
+func (s *SteamGuardAccount) DenyAuthSession(info *AuthSessionInfo) error {
+	return s.DenyAuthSessionContext(context.Background(), info)
+}
+
+// DenyAuthSessionContext is DenyAuthSession with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) DenyAuthSessionContext(ctx context.Context, info *AuthSessionInfo) error {
+	return s.updateAuthSession(ctx, info, false)
+}
+
+func (s *SteamGuardAccount) updateAuthSession(ctx context.Context, info *AuthSessionInfo, confirm bool) error {
+	if s.Session == nil {
+		return ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return err
+	}
+
+	signature, err := s.generateAuthSessionSignature(info.Version, info.ClientID, s.Session.SteamID)
+	if err != nil {
+		return err
+	}
+
+	c := s.steamClient()
+	updateRequest := authUpdateAuthSessionWithMobileConfirmationRequest{
+		Version:     info.Version,
+		ClientID:    info.ClientID,
+		SteamID:     s.Session.SteamID,
+		Signature:   signature,
+		Confirm:     confirm,
+		Persistence: sessionPersistent,
+	}
+
+	c.logf("Updating auth session %d, approved %t", info.ClientID, confirm)
+	_, err = c.SteamWeb().
+		SetContext(ctx).
+		SetAccessToken(s.Session.accessToken()).
+		Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/UpdateAuthSessionWithMobileConfirmation/v1").
+		SetProtobuf(&updateRequest).
+		HandleProtobuf(&protoEmpty{}).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error: %s", err)
+	}
+
+	action := AuditDenyAuthSession
+	if confirm {
+		action = AuditApproveAuthSession
+	}
+	c.audit(s, action, strconv.FormatUint(info.ClientID, 10), strings.TrimSpace(info.DeviceFriendlyName+" "+info.IP), err)
+
+	return err
+}
+
+// generateAuthSessionSignature proves to steam we hold the shared secret,
+// it's a HMAC-SHA256 of the version, client id and steam id little endian
+func (s *SteamGuardAccount) generateAuthSessionSignature(version int32, clientID uint64, steamID SteamID) ([]byte, error) {
+	if s.SharedSecret == "" {
+		return nil, ErrInvalidSharedSecret
+	}
+
+	sharedSecret, err := base64.StdEncoding.DecodeString(s.SharedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSharedSecret, err)
+	}
+
+	buf := make([]byte, 2+8+8)
+	binary.LittleEndian.PutUint16(buf, uint16(version))
+	binary.LittleEndian.PutUint64(buf[2:], clientID)
+	binary.LittleEndian.PutUint64(buf[10:], uint64(steamID))
+
+	mac := hmac.New(sha256.New, sharedSecret)
+	mac.Write(buf)
+	return mac.Sum(nil), nil
+}
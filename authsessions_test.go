@@ -0,0 +1,88 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestApproveAuthSession(t *testing.T) {
+	secret := []byte("0123456789abcdefghij")
+	steamID := SteamID(76561198263585543)
+
+	// version 1, client id 42 and the steam id, little endian
+	expected := hmac.New(sha256.New, secret)
+	expected.Write([]byte{1, 0, 42, 0, 0, 0, 0, 0, 0, 0, 7, 75, 20, 18, 1, 0, 16, 1})
+	expectedSignature := expected.Sum(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/GetAuthSessionsForAccount/v1", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		response := protoWriter{}
+		response.bytes(1, []byte{42})
+		w.Write(response)
+	})
+	mux.HandleFunc("/IAuthenticationService/GetAuthSessionInfo/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		response := protoWriter{}
+		response.string(1, "127.0.0.1")
+		response.string(7, "desktop")
+		response.varint(8, 1)
+		w.Write(response)
+	})
+	approved := false
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithMobileConfirmation/v1", func(w http.ResponseWriter, r *http.Request) {
+		var signature []byte
+		rangeProtoFields(protoRequest(r), func(f protoField) error {
+			switch f.num {
+			case 4:
+				signature = f.bytes()
+			case 5:
+				approved = f.bool()
+			}
+			return nil
+		})
+		if !bytes.Equal(signature, expectedSignature) {
+			w.Header().Set("X-eresult", "121")
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+
+	account := &SteamGuardAccount{
+		SharedSecret: base64.StdEncoding.EncodeToString(secret),
+		Session:      &SessionData{SteamID: steamID, AccessToken: "access"},
+	}
+	account.SetClient(client)
+
+	sessions, err := account.FetchAuthSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ClientID != 42 || sessions[0].DeviceFriendlyName != "desktop" || sessions[0].Version != 1 {
+		t.Fatalf("sessions mismatched %#v", sessions)
+	}
+
+	if err := account.ApproveAuthSession(sessions[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !approved {
+		t.Error("session was not approved")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+	"time"
+)
+
+// Client owns everything needed to talk to steam, the endpoints,
+// the http client, the time aligner and the logger. Use more than
+// one if you need differently configured setups in the one process.
+type Client struct {
+	Endpoints  *Endpoints
+	HTTPClient *http.Client
+
+	// RefreshWithin is how close to expiry an access token is allowed
+	// to get before it's refreshed ahead of a confirmation request,
+	// zero disables the automatic refresh
+	RefreshWithin time.Duration
+
+	// ConfirmationBatchSize is the most confirmations answered in
+	// a single request by AcceptConfirmations and RejectConfirmations
+	ConfirmationBatchSize int
+
+	// Audit records confirmations answered, sign ins approved and
+	// authenticators added or removed, nil records nothing
+	Audit AuditSink
+
+	// Notifier is told about new confirmations and authenticators
+	// added or removed, nil tells no one
+	Notifier Notifier
+
+	// NotifyTimeout is how long the Notifier gets to deliver a
+	// notification, it's sent in the background so nothing waits on it
+	NotifyTimeout time.Duration
+
+	timeAligner *timeAligner
+
+	notifyMu sync.Mutex
+	notified map[SteamID]map[string]bool
+	notifyWG sync.WaitGroup
+
+	logger           logLogger
+	wantLogRequests  bool
+	wantLogResponses bool
+	wantLogCookies   bool
+}
+
+// DefaultClient is the Client used by the package level functions
+var DefaultClient = newDefaultClient()
+
+// NewClient allocates and returns a new Client using the
+// default steam endpoints
+func NewClient() *Client {
+	endpoints := defaultEndpoints()
+	return newClient(&endpoints)
+}
+
+func newDefaultClient() *Client {
+	return newClient(&APIEndpoints)
+}
+
+func newClient(endpoints *Endpoints) *Client {
+	c := &Client{
+		Endpoints:             endpoints,
+		HTTPClient:            &http.Client{},
+		RefreshWithin:         5 * time.Minute,
+		ConfirmationBatchSize: 30,
+		NotifyTimeout:         30 * time.Second,
+	}
+	c.timeAligner = &timeAligner{client: c}
+	return c
+}
+
+// TimeAligner returns the time aligner bound to this client
+func (c *Client) TimeAligner() *timeAligner {
+	return c.timeAligner
+}
+
+// SteamWeb returns a chainable steamWeb object bound to this client
+func (c *Client) SteamWeb() *steamWeb {
+	return newSteamWeb(c)
+}
+
+// NewUserLogin allocates and returns a new UserLogin bound to this client
+func (c *Client) NewUserLogin(username, password string) *UserLogin {
+	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
+	return &UserLogin{
+		client:    c,
+		cookieJar: cookieJar,
+		Session:   &SessionData{},
+		Username:  username,
+		Password:  password}
+}
+
+// NewAuthSessionLogin allocates and returns a new AuthSessionLogin bound to this client
+func (c *Client) NewAuthSessionLogin(username, password string) *AuthSessionLogin {
+	return &AuthSessionLogin{
+		client:   c,
+		Session:  &SessionData{},
+		Username: username,
+		Password: password}
+}
+
+// NewAuthenticatorLinker will create an account linker bound to this client
+func (c *Client) NewAuthenticatorLinker(session *SessionData) *AuthenticatorLinker {
+	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
+
+	c.setSessionCookies(session, cookieJar)
+
+	return &AuthenticatorLinker{
+		client:    c,
+		session:   session,
+		DeviceID:  generateDeviceID(),
+		cookieJar: cookieJar,
+	}
+}
+
+func (c *Client) setSessionCookies(session *SessionData, jar http.CookieJar) {
+	session.setCookies(jar, c.Endpoints.CommunityBase)
+}
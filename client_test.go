@@ -0,0 +1,116 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClientAlignTime(t *testing.T) {
+	serverTime := time.Now().Add(time.Hour).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := twoFactorTimeRequest{}
+		rangeProtoFields(protoRequest(r), func(f protoField) error {
+			request.SenderTime = f.u
+			return nil
+		})
+		if request.SenderTime == 0 {
+			t.Errorf("missing sender time in %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		response := protoWriter{}
+		response.varint(1, uint64(serverTime))
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.TwoFactorTimeQuery, _ = url.Parse(server.URL + "/ITwoFactorService/QueryTime/v0001")
+
+	if client.Endpoints.TwoFactorTimeQuery == APIEndpoints.TwoFactorTimeQuery {
+		t.Fatal("client endpoints shared with the default client")
+	}
+
+	steamTime := client.TimeAligner().GetSteamTime()
+	if diff := steamTime.Sub(time.Now()); diff < 59*time.Minute || diff > 61*time.Minute {
+		t.Errorf("time not aligned, difference was %s", diff)
+	}
+
+	if TimeAligner.aligned {
+		t.Error("default time aligner was aligned by another client")
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient()
+	client.Endpoints.TwoFactorTimeQuery, _ = url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- client.TimeAligner().AlignTimeContext(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be returned, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not aborted by the context deadline")
+	}
+
+	if client.TimeAligner().aligned {
+		t.Error("time aligned despite cancelled request")
+	}
+	if _, err := client.TimeAligner().GetSteamTimeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected steam time to fail with the deadline, got %v", err)
+	}
+}
+
+func TestClientSessionCookies(t *testing.T) {
+	cookies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cookie := range r.Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"conf":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.CommunityBase, _ = url.Parse(server.URL)
+	client.TimeAligner().aligned = true
+
+	account := &SteamGuardAccount{
+		IdentitySecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		DeviceID:       "android:test",
+		Session:        &SessionData{SteamID: 76561198263585543, SessionID: "session", SteamLoginSecure: "secure"},
+	}
+	account.SetClient(client)
+
+	if _, err := account.FetchConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+	if cookies["steamLoginSecure"] != "secure" || cookies["sessionid"] != "session" || cookies["steamid"] != "76561198263585543" {
+		t.Errorf("session cookies not sent to the community endpoint, got %v", cookies)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"html"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// steamID64Base is added to a 32 bit account id to make a SteamID
+	steamID64Base = 76561197960265728
+
+	// detailsItemsGiven and detailsItemsReceived mark the start of the
+	// items we'd give and receive
+	detailsItemsGiven    = "tradeoffer_items primary"
+	detailsItemsReceived = "tradeoffer_items secondary"
+)
+
+var (
+	detailsTradeOfferIDRegex  = regexp.MustCompile(`id="tradeofferid_(\d+)"`)
+	detailsMiniProfileRegex   = regexp.MustCompile(`data-miniprofile="(\d+)"`)
+	detailsPartnerNameRegex   = regexp.MustCompile(`class="trade_partner_headline_sub"[^>]*>(?:\s*<[^>]+>)*\s*([^<]+?)\s*<`)
+	detailsEconomyItemRegex   = regexp.MustCompile(`data-economy-item="classinfo/(\d+)/(\d+)(?:/(\d+))?"`)
+	detailsListingPricesRegex = regexp.MustCompile(`(?s)class="mobileconf_listing_prices"[^>]*>(.*?)</div>`)
+	detailsTagRegex           = regexp.MustCompile(`<[^>]+>`)
+	detailsWhitespaceRegex    = regexp.MustCompile(`\s+`)
+)
+
+// ConfirmationDetails is what steam shows when you tap on a
+// confirmation, HTML is the page as is and the rest is picked out
+// of it where it could be, anything not found is left empty
+type ConfirmationDetails struct {
+	HTML string
+
+	TradeOfferID        uint64
+	TradePartnerName    string
+	TradePartnerSteamID SteamID
+	ItemsGiven          []ConfirmationItem
+	ItemsReceived       []ConfirmationItem
+	// ItemsFound is set when both item lists were on the page, without
+	// it no items given doesn't mean nothing is given
+	ItemsFound bool
+
+	MarketPrice string
+}
+
+// ConfirmationItem identifies an item in a trade by its class
+type ConfirmationItem struct {
+	AppID      uint32
+	ClassID    uint64
+	InstanceID uint64
+}
+
+type confirmationDetailsResponse struct {
+	Success  bool   `json:"success"`
+	NeedAuth bool   `json:"needauth"`
+	HTML     string `json:"html"`
+}
+
+// FetchConfirmationDetails gets the details page for the given confirmation
+func (s *SteamGuardAccount) FetchConfirmationDetails(conf *Confirmation) (*ConfirmationDetails, error) {
+	return s.FetchConfirmationDetailsContext(context.Background(), conf)
+}
+
+// FetchConfirmationDetailsContext is FetchConfirmationDetails with a
+// context that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) FetchConfirmationDetailsContext(ctx context.Context, conf *Confirmation) (*ConfirmationDetails, error) {
+	if s.Session == nil {
+		return nil, ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return nil, err
+	}
+
+	c := s.steamClient()
+	urlStr := c.Endpoints.CommunityBase.String() + "/mobileconf/details/" + url.PathEscape(conf.ConfirmationID)
+	query, err := s.generateConfirmationQueryParams(ctx, "details"+conf.ConfirmationID)
+	if err != nil {
+		return nil, err
+	}
+
+	detailsResponse := confirmationDetailsResponse{}
+	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
+	c.setSessionCookies(s.Session, cookieJar)
+
+	c.logf("requesting details for confirmation %s", conf.ConfirmationID)
+	_, err = c.SteamWeb().
+		SetContext(ctx).
+		SetJar(cookieJar).
+		SetParams(query).
+		Get(urlStr).
+		HandleJSON(&detailsResponse).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error: %s", err)
+		return nil, err
+	}
+
+	if detailsResponse.NeedAuth {
+		return nil, ErrSessionExpired
+	}
+
+	if !detailsResponse.Success {
+		return nil, &SteamError{Op: "fetch confirmation details " + conf.ConfirmationID}
+	}
+
+	return parseConfirmationDetails(detailsResponse.HTML), nil
+}
+
+// parseConfirmationDetails picks what it can out of the details html,
+// there's only so much you can trust a regex with so it's best effort
+func parseConfirmationDetails(body string) *ConfirmationDetails {
+	details := &ConfirmationDetails{HTML: body}
+
+	if m := detailsTradeOfferIDRegex.FindStringSubmatch(body); m != nil {
+		details.TradeOfferID, _ = strconv.ParseUint(m[1], 10, 64)
+	}
+
+	if m := detailsMiniProfileRegex.FindStringSubmatch(body); m != nil {
+		if accountID, err := strconv.ParseUint(m[1], 10, 32); err == nil {
+			details.TradePartnerSteamID = SteamID(steamID64Base + accountID)
+		}
+	}
+
+	if m := detailsPartnerNameRegex.FindStringSubmatch(body); m != nil {
+		details.TradePartnerName = html.UnescapeString(m[1])
+	}
+
+	// Items we give are listed first, the ones we receive come after
+	// the secondary item list starts
+	given, received := strings.Index(body, detailsItemsGiven), strings.Index(body, detailsItemsReceived)
+	if given >= 0 && received > given {
+		details.ItemsFound = true
+		details.ItemsGiven = parseConfirmationItems(body[given:received])
+		details.ItemsReceived = parseConfirmationItems(body[received:])
+	}
+
+	if m := detailsListingPricesRegex.FindStringSubmatch(body); m != nil {
+		text := detailsTagRegex.ReplaceAllString(m[1], " ")
+		details.MarketPrice = strings.TrimSpace(detailsWhitespaceRegex.ReplaceAllString(html.UnescapeString(text), " "))
+	}
+
+	return details
+}
+
+func parseConfirmationItems(body string) []ConfirmationItem {
+	var items []ConfirmationItem
+	for _, m := range detailsEconomyItemRegex.FindAllStringSubmatch(body, -1) {
+		appID, _ := strconv.ParseUint(m[1], 10, 32)
+		item := ConfirmationItem{AppID: uint32(appID)}
+		item.ClassID, _ = strconv.ParseUint(m[2], 10, 64)
+		item.InstanceID, _ = strconv.ParseUint(m[3], 10, 64)
+		items = append(items, item)
+	}
+	return items
+}
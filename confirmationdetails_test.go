@@ -0,0 +1,75 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestFetchConfirmationDetails(t *testing.T) {
+	page := `<div class="mobileconf_trade_area">
+	<div class="tradeoffer" id="tradeofferid_4242">
+		<div class="tradeoffer_partner">
+			<div class="playerAvatar" data-miniprofile="303319815"></div>
+		</div>
+		<div class="trade_partner_header">
+			<span class="trade_partner_headline_sub"><a href="https://steamcommunity.com/profiles/76561198263585543">Some &amp; One</a></span>
+		</div>
+		<div class="tradeoffer_items primary">
+			<div class="trade_item" data-economy-item="classinfo/730/111/0"></div>
+		</div>
+		<div class="tradeoffer_items secondary">
+			<div class="trade_item" data-economy-item="classinfo/440/222/333"></div>
+			<div class="trade_item" data-economy-item="classinfo/440/444"></div>
+		</div>
+	</div>
+</div>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/details/11", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tag") != "details11" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "html": page})
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	details, err := account.FetchConfirmationDetails(&Confirmation{ConfirmationID: "11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.HTML != page || details.TradeOfferID != 4242 {
+		t.Errorf("details mismatched %#v", details)
+	}
+	if details.TradePartnerSteamID != SteamID(76561198263585543) || details.TradePartnerName != "Some & One" {
+		t.Errorf("trade partner mismatched %d %q", details.TradePartnerSteamID, details.TradePartnerName)
+	}
+	if len(details.ItemsGiven) != 1 || details.ItemsGiven[0] != (ConfirmationItem{AppID: 730, ClassID: 111}) {
+		t.Errorf("items given mismatched %#v", details.ItemsGiven)
+	}
+	if len(details.ItemsReceived) != 2 || details.ItemsReceived[0] != (ConfirmationItem{AppID: 440, ClassID: 222, InstanceID: 333}) {
+		t.Errorf("items received mismatched %#v", details.ItemsReceived)
+	}
+}
+
+func TestParseConfirmationDetailsMarket(t *testing.T) {
+	details := parseConfirmationDetails(`<div class="mobileconf_listing_prices">
+		You receive: <br>
+		$0.87 ($1.00)
+	</div>`)
+
+	if details.MarketPrice != "You receive: $0.87 ($1.00)" {
+		t.Errorf("market price mismatched %q", details.MarketPrice)
+	}
+	if details.TradeOfferID != 0 || len(details.ItemsGiven) != 0 {
+		t.Errorf("expected no trade details, got %#v", details)
+	}
+}
@@ -4,9 +4,141 @@
 
 package steamauth
 
+import (
+	"strconv"
+	"time"
+)
+
 // Confirmation storage
 type Confirmation struct {
-	ConfirmationID          string
-	ConfirmationKey         string
+	ConfirmationID  string
+	ConfirmationKey string
+	// ConfirmationDescription is the headline, kept for those
+	// that were using the description from the old html page
 	ConfirmationDescription string
+
+	Type     ConfirmationType
+	TypeName string
+	// CreatorID is the trade offer id for trades and the
+	// listing id for market listings
+	CreatorID    uint64
+	Headline     string
+	Summary      []string
+	Icon         string
+	CreationTime time.Time
+	AcceptLabel  string
+	CancelLabel  string
+}
+
+// ConfirmationType is the kind of thing a confirmation is for
+type ConfirmationType int
+
+// Known ConfirmationTypes
+const (
+	ConfirmationTypeUnknown           ConfirmationType = 0
+	ConfirmationTypeGeneric           ConfirmationType = 1
+	ConfirmationTypeTrade             ConfirmationType = 2
+	ConfirmationTypeMarketListing     ConfirmationType = 3
+	ConfirmationTypeFeatureOptOut     ConfirmationType = 4
+	ConfirmationTypePhoneNumberChange ConfirmationType = 5
+	ConfirmationTypeAccountRecovery   ConfirmationType = 6
+	ConfirmationTypeAPIKey            ConfirmationType = 9
+	ConfirmationTypeFamilyJoin        ConfirmationType = 11
+)
+
+var confirmationTypes = map[ConfirmationType]string{
+	ConfirmationTypeUnknown:           "unknown",
+	ConfirmationTypeGeneric:           "generic",
+	ConfirmationTypeTrade:             "trade",
+	ConfirmationTypeMarketListing:     "market listing",
+	ConfirmationTypeFeatureOptOut:     "feature opt out",
+	ConfirmationTypePhoneNumberChange: "phone number change",
+	ConfirmationTypeAccountRecovery:   "account recovery",
+	ConfirmationTypeAPIKey:            "api key",
+	ConfirmationTypeFamilyJoin:        "family join",
+}
+
+func (t ConfirmationType) String() string {
+	if s, ok := confirmationTypes[t]; ok {
+		return s
+	}
+	return "unknown confirmation type " + strconv.Itoa(int(t))
+}
+
+// TradeOfferID returns the id of the trade offer this confirmation
+// belongs to, false if it isn't a trade confirmation
+func (c *Confirmation) TradeOfferID() (uint64, bool) {
+	return c.CreatorID, c.Type == ConfirmationTypeTrade && c.CreatorID != 0
+}
+
+// MarketListingID returns the id of the market listing this
+// confirmation belongs to, false if it isn't a market confirmation
+func (c *Confirmation) MarketListingID() (uint64, bool) {
+	return c.CreatorID, c.Type == ConfirmationTypeMarketListing && c.CreatorID != 0
+}
+
+// FindTradeOfferConfirmation returns the confirmation for the
+// given trade offer, nil if there isn't one
+func FindTradeOfferConfirmation(confs []*Confirmation, tradeOfferID uint64) *Confirmation {
+	return findConfirmation(confs, ConfirmationTypeTrade, tradeOfferID)
+}
+
+// FindMarketListingConfirmation returns the confirmation for the
+// given market listing, nil if there isn't one
+func FindMarketListingConfirmation(confs []*Confirmation, listingID uint64) *Confirmation {
+	return findConfirmation(confs, ConfirmationTypeMarketListing, listingID)
+}
+
+func findConfirmation(confs []*Confirmation, confType ConfirmationType, creatorID uint64) *Confirmation {
+	for _, conf := range confs {
+		if conf.Type == confType && conf.CreatorID == creatorID {
+			return conf
+		}
+	}
+	return nil
+}
+
+// confirmationListResponse is what /mobileconf/getlist returns
+type confirmationListResponse struct {
+	Success  bool               `json:"success"`
+	NeedAuth bool               `json:"needauth"`
+	Message  string             `json:"message"`
+	Detail   string             `json:"detail"`
+	Conf     []confirmationJSON `json:"conf"`
+}
+
+type confirmationJSON struct {
+	Type         ConfirmationType `json:"type"`
+	TypeName     string           `json:"type_name"`
+	ID           string           `json:"id"`
+	CreatorID    string           `json:"creator_id"`
+	Nonce        string           `json:"nonce"`
+	CreationTime int64            `json:"creation_time"`
+	Cancel       string           `json:"cancel"`
+	Accept       string           `json:"accept"`
+	Icon         string           `json:"icon"`
+	Headline     string           `json:"headline"`
+	Summary      []string         `json:"summary"`
+}
+
+func (c *confirmationJSON) confirmation() (*Confirmation, error) {
+	creatorID, err := strconv.ParseUint(c.CreatorID, 10, 64)
+	if err != nil && c.CreatorID != "" {
+		return nil, err
+	}
+
+	return &Confirmation{
+		ConfirmationID:          c.ID,
+		ConfirmationKey:         c.Nonce,
+		ConfirmationDescription: c.Headline,
+		Type:                    c.Type,
+		TypeName:                c.TypeName,
+		CreatorID:               creatorID,
+		Headline:                c.Headline,
+		Summary:                 c.Summary,
+		Icon:                    c.Icon,
+		CreationTime:            time.Unix(c.CreationTime, 0),
+		AcceptLabel:             c.Accept,
+		CancelLabel:             c.Cancel,
+	}, nil
 }
@@ -0,0 +1,174 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newConfirmationTestAccount(t *testing.T, handler http.Handler) (*SteamGuardAccount, func()) {
+	server := httptest.NewServer(handler)
+
+	client := NewClient()
+	client.Endpoints.CommunityBase, _ = url.Parse(server.URL)
+	client.TimeAligner().aligned = true
+
+	account := &SteamGuardAccount{
+		IdentitySecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		DeviceID:       "android:test",
+		Session:        &SessionData{SteamID: 76561198263585543, SteamLoginSecure: "secure"},
+	}
+	account.SetClient(client)
+
+	return account, server.Close
+}
+
+func TestFetchConfirmations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tag") != "conf" || r.URL.Query().Get("k") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"conf":[{"type":2,"type_name":"Trade Offer","id":"11","creator_id":"4242","nonce":"99","creation_time":1700000000,"cancel":"Cancel","accept":"Send Offer","icon":"https://example.com/a.jpg","multi":false,"headline":"partner","summary":["You will give up 1 item","You will receive 2 items"],"warn":null}]}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	confirmations, err := account.FetchConfirmations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmations) != 1 {
+		t.Fatalf("expected 1 confirmation, got %d", len(confirmations))
+	}
+
+	conf := confirmations[0]
+	if conf.ConfirmationID != "11" || conf.ConfirmationKey != "99" || conf.CreatorID != 4242 || conf.Type != ConfirmationTypeTrade {
+		t.Errorf("confirmation mismatched %#v", conf)
+	}
+	if conf.Headline != "partner" || conf.ConfirmationDescription != "partner" || len(conf.Summary) != 2 {
+		t.Errorf("confirmation text mismatched %#v", conf)
+	}
+	if !conf.CreationTime.Equal(time.Unix(1700000000, 0)) || conf.AcceptLabel != "Send Offer" || conf.CancelLabel != "Cancel" {
+		t.Errorf("confirmation details mismatched %#v", conf)
+	}
+}
+
+func TestFetchConfirmationsNeedAuth(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":false,"needauth":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	if _, err := account.FetchConfirmations(); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestConfirmationCorrelation(t *testing.T) {
+	confs := []*Confirmation{
+		{ConfirmationID: "1", Type: ConfirmationTypeMarketListing, CreatorID: 4242},
+		{ConfirmationID: "2", Type: ConfirmationTypeTrade, CreatorID: 4242},
+		{ConfirmationID: "3", Type: ConfirmationTypeAPIKey},
+	}
+
+	if conf := FindTradeOfferConfirmation(confs, 4242); conf == nil || conf.ConfirmationID != "2" {
+		t.Errorf("expected trade confirmation 2, got %#v", conf)
+	}
+	if conf := FindMarketListingConfirmation(confs, 4242); conf == nil || conf.ConfirmationID != "1" {
+		t.Errorf("expected market confirmation 1, got %#v", conf)
+	}
+	if conf := FindTradeOfferConfirmation(confs, 1); conf != nil {
+		t.Errorf("expected no confirmation, got %#v", conf)
+	}
+
+	if id, ok := confs[1].TradeOfferID(); !ok || id != 4242 {
+		t.Errorf("expected trade offer 4242, got %d %t", id, ok)
+	}
+	if _, ok := confs[1].MarketListingID(); ok {
+		t.Error("trade confirmation should not have a market listing")
+	}
+
+	if ConfirmationTypeTrade.String() != "trade" || ConfirmationType(99).String() != "unknown confirmation type 99" {
+		t.Error("confirmation type strings mismatched")
+	}
+}
+
+func TestAcceptConfirmationsBatches(t *testing.T) {
+	var batches [][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/multiajaxop", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Method != http.MethodPost || r.PostForm.Get("op") != "allow" || r.PostForm.Get("tag") != "allow" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(r.PostForm["cid[]"]) != len(r.PostForm["ck[]"]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, r.PostForm["cid[]"])
+		w.Header().Set("Content-Type", "application/json")
+		if len(batches) == 2 {
+			w.Write([]byte(`{"success":false,"message":"nope"}`))
+			return
+		}
+		w.Write([]byte(`{"success":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+	account.steamClient().ConfirmationBatchSize = 2
+
+	confs := make([]*Confirmation, 5)
+	for i := range confs {
+		confs[i] = &Confirmation{ConfirmationID: strconv.Itoa(i), ConfirmationKey: "key"}
+	}
+
+	results, err := account.AcceptConfirmations(confs)
+	if !errors.Is(err, ErrSteamRejected) {
+		t.Errorf("expected the second batch to be rejected, got %v", err)
+	}
+	if len(batches) != 3 || len(results) != 3 {
+		t.Fatalf("expected 3 batches, sent %d and got %d results", len(batches), len(results))
+	}
+	if batches[2][0] != "4" || len(results[2].Confirmations) != 1 {
+		t.Errorf("last batch mismatched %v", batches[2])
+	}
+	if results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Errorf("batch errors mismatched %v %v %v", results[0].Err, results[1].Err, results[2].Err)
+	}
+}
+
+func TestRejectConfirmationsSessionExpired(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/multiajaxop", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":false,"needauth":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+	account.steamClient().ConfirmationBatchSize = 1
+
+	results, err := account.RejectConfirmations([]*Confirmation{{ConfirmationID: "1"}, {ConfirmationID: "2"}})
+	if !errors.Is(err, ErrSessionExpired) || requests != 1 {
+		t.Errorf("expected one request and ErrSessionExpired, got %d %v", requests, err)
+	}
+	if len(results) != 2 || !errors.Is(results[1].Err, ErrSessionExpired) {
+		t.Errorf("expected the remaining batch to carry the error, got %#v", results)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ConfirmationWatcher polls an account for confirmations and tells
+// you when they come and go, failed polls back off exponentially
+// and being rate limited backs off all the way so steam's rate
+// limits on mobileconf aren't made worse
+type ConfirmationWatcher struct {
+	// Interval between polls, defaults to 30 seconds
+	Interval time.Duration
+	// MaxBackoff caps the delay between polls after failures,
+	// defaults to 5 minutes
+	MaxBackoff time.Duration
+	// Jitter randomly moves each delay by up to this fraction of
+	// it so a fleet of watchers don't all poll at once, defaults
+	// to 0.1, negative disables it
+	Jitter float64
+
+	account *SteamGuardAccount
+	known   map[string]*Confirmation
+}
+
+// ConfirmationEventType is the kind of ConfirmationEvent
+type ConfirmationEventType int
+
+// Various ConfirmationEventTypes.
+// You can call .String() to get a human representation.
+const (
+	ConfirmationNew ConfirmationEventType = iota
+	ConfirmationRemoved
+	ConfirmationPollFailed
+)
+
+var confirmationEventTypes = []string{
+	ConfirmationNew:        "new",
+	ConfirmationRemoved:    "removed",
+	ConfirmationPollFailed: "poll failed",
+}
+
+func (t ConfirmationEventType) String() string {
+	return confirmationEventTypes[t]
+}
+
+// ConfirmationEvent is sent by a ConfirmationWatcher, Confirmation is
+// set for ConfirmationNew and ConfirmationRemoved and Err for
+// ConfirmationPollFailed
+type ConfirmationEvent struct {
+	Type         ConfirmationEventType
+	Confirmation *Confirmation
+	Err          error
+}
+
+// NewConfirmationWatcher returns a watcher for this account's confirmations
+func (s *SteamGuardAccount) NewConfirmationWatcher() *ConfirmationWatcher {
+	return &ConfirmationWatcher{
+		Interval:   30 * time.Second,
+		MaxBackoff: 5 * time.Minute,
+		Jitter:     0.1,
+		account:    s,
+	}
+}
+
+// Watch polls in the background and sends events on the returned
+// channel, it's closed once ctx is done
+func (w *ConfirmationWatcher) Watch(ctx context.Context) <-chan ConfirmationEvent {
+	events := make(chan ConfirmationEvent)
+	go func() {
+		defer close(events)
+		w.Run(ctx, func(event ConfirmationEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return events
+}
+
+// Run polls until ctx is done calling fn for every event, it
+// returns ctx.Err()
+func (w *ConfirmationWatcher) Run(ctx context.Context, fn func(ConfirmationEvent)) error {
+	failures := 0
+	for {
+		rateLimited := false
+		if err := w.poll(ctx, fn); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures++
+			rateLimited = errors.Is(err, ErrRateLimited)
+			w.account.steamClient().logf("Confirmation poll failed (%d in a row): %s", failures, err)
+			fn(ConfirmationEvent{Type: ConfirmationPollFailed, Err: err})
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.delay(failures, rateLimited)):
+		}
+	}
+}
+
+// poll fetches the confirmations and diffs them against the last set
+func (w *ConfirmationWatcher) poll(ctx context.Context, fn func(ConfirmationEvent)) error {
+	confs, err := w.account.FetchConfirmationsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]*Confirmation, len(confs))
+	for _, conf := range confs {
+		current[conf.ConfirmationID] = conf
+		if _, ok := w.known[conf.ConfirmationID]; !ok {
+			fn(ConfirmationEvent{Type: ConfirmationNew, Confirmation: conf})
+		}
+	}
+
+	for id, conf := range w.known {
+		if _, ok := current[id]; !ok {
+			fn(ConfirmationEvent{Type: ConfirmationRemoved, Confirmation: conf})
+		}
+	}
+
+	w.known = current
+	return nil
+}
+
+// delay is the interval doubled for every failure in a row, or
+// straight to the max if steam said we're going too fast, capped at
+// MaxBackoff, with jitter applied
+func (w *ConfirmationWatcher) delay(failures int, rateLimited bool) time.Duration {
+	maxBackoff := w.maxBackoff()
+
+	delay := w.interval()
+	if rateLimited {
+		delay = maxBackoff
+	}
+	for i := 0; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	if jitter := w.jitter(); jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay))
+	}
+
+	return delay
+}
+
+func (w *ConfirmationWatcher) interval() time.Duration {
+	if w.Interval <= 0 {
+		return 30 * time.Second
+	}
+	return w.Interval
+}
+
+func (w *ConfirmationWatcher) maxBackoff() time.Duration {
+	if w.MaxBackoff <= 0 {
+		return 5 * time.Minute
+	}
+	return w.MaxBackoff
+}
+
+func (w *ConfirmationWatcher) jitter() float64 {
+	if w.Jitter == 0 {
+		return 0.1
+	}
+	return w.Jitter
+}
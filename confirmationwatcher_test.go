@@ -0,0 +1,121 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConfirmationWatcher(t *testing.T) {
+	pages := []string{
+		`{"success":true,"conf":[{"id":"1","nonce":"1"},{"id":"2","nonce":"2"}]}`,
+		`{"success":true,"conf":[{"id":"1","nonce":"1"},{"id":"2","nonce":"2"}]}`,
+		``,
+		`{"success":true,"conf":[{"id":"2","nonce":"2"},{"id":"3","nonce":"3"}]}`,
+	}
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		page := pages[len(pages)-1]
+		if polls < len(pages) {
+			page = pages[polls]
+		}
+		polls++
+		if page == "" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(page))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	watcher := account.NewConfirmationWatcher()
+	watcher.Interval = time.Millisecond
+	watcher.MaxBackoff = 10 * time.Millisecond
+	watcher.Jitter = -1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []ConfirmationEvent
+	for event := range watcher.Watch(ctx) {
+		got = append(got, event)
+		if len(got) == 5 {
+			cancel()
+		}
+	}
+
+	if len(got) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(got))
+	}
+
+	news := map[string]bool{}
+	for _, event := range got[:2] {
+		if event.Type != ConfirmationNew {
+			t.Errorf("expected new, got %s", event.Type)
+		}
+		news[event.Confirmation.ConfirmationID] = true
+	}
+	if !news["1"] || !news["2"] {
+		t.Errorf("expected 1 and 2 to be new, got %v", news)
+	}
+
+	if got[2].Type != ConfirmationPollFailed || !errors.Is(got[2].Err, ErrRateLimited) {
+		t.Errorf("expected a rate limited poll, got %s %v", got[2].Type, got[2].Err)
+	}
+
+	if got[3].Type != ConfirmationNew || got[3].Confirmation.ConfirmationID != "3" {
+		t.Errorf("expected 3 to be new, got %s %#v", got[3].Type, got[3].Confirmation)
+	}
+	if got[4].Type != ConfirmationRemoved || got[4].Confirmation.ConfirmationID != "1" {
+		t.Errorf("expected 1 to be removed, got %s %#v", got[4].Type, got[4].Confirmation)
+	}
+}
+
+func TestConfirmationWatcherDelay(t *testing.T) {
+	watcher := &ConfirmationWatcher{Interval: time.Second, MaxBackoff: 10 * time.Second, Jitter: -1}
+
+	for failures, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if delay := watcher.delay(failures, false); delay != expected {
+			t.Errorf("expected %s after %d failures, got %s", expected, failures, delay)
+		}
+	}
+	if delay := watcher.delay(1, true); delay != 10*time.Second {
+		t.Errorf("expected to back off all the way when rate limited, got %s", delay)
+	}
+
+	watcher.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if delay := watcher.delay(0, false); delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Fatalf("jittered delay out of range %s", delay)
+		}
+	}
+}
+
+func TestConfirmationWatcherDelayDefaults(t *testing.T) {
+	watcher := &ConfirmationWatcher{Jitter: -1}
+
+	for failures, expected := range []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if delay := watcher.delay(failures, false); delay != expected {
+			t.Errorf("expected %s after %d failures, got %s", expected, failures, delay)
+		}
+	}
+	if delay := watcher.delay(1, true); delay != 5*time.Minute {
+		t.Errorf("expected to back off all the way when rate limited, got %s", delay)
+	}
+
+	watcher = &ConfirmationWatcher{Interval: -time.Second, MaxBackoff: -time.Second}
+	for i := 0; i < 100; i++ {
+		if delay := watcher.delay(0, false); delay < 27*time.Second || delay > 33*time.Second {
+			t.Fatalf("expected the default interval with the default jitter, got %s", delay)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const envelopeVersion = 1
+
+// envelopeKDF is used for everything newly encrypted, what was used is
+// kept in the header so these can change without breaking old files
+var envelopeKDF = envelopeKDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// envelope is the encrypted format, the header (everything but the
+// ciphertext) is authenticated along with it so it can't be altered
+type envelope struct {
+	envelopeHeader
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type envelopeHeader struct {
+	Version int               `json:"version"`
+	KDF     string            `json:"kdf"`
+	Params  envelopeKDFParams `json:"kdf_params"`
+	Salt    []byte            `json:"salt"`
+	Cipher  string            `json:"cipher"`
+	Nonce   []byte            `json:"nonce"`
+}
+
+type envelopeKDFParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// SaveEncrypted writes the account to an io.Writer encrypted with the
+// passphrase, the key is derived with argon2id and the account sealed
+// with AES-256-GCM. Nothing is written in the clear
+func (s *SteamGuardAccount) SaveEncrypted(w io.Writer, passphrase string) error {
+	plaintext, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	defer zero(plaintext)
+
+	env, err := sealEnvelope(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(env)
+}
+
+// LoadEncrypted reads an account written by SaveEncrypted, a passphrase
+// that doesn't match fails with ErrBadPasskey and a format this doesn't
+// know with ErrUnsupportedVersion
+func (s *SteamGuardAccount) LoadEncrypted(r io.Reader, passphrase string) error {
+	plaintext, err := openEnvelope(r, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zero(plaintext)
+
+	return json.Unmarshal(plaintext, s)
+}
+
+// RotateEncrypted re-encrypts what SaveEncrypted wrote under a new
+// passphrase, the account is only ever decrypted in memory
+func RotateEncrypted(r io.Reader, w io.Writer, oldPassphrase, newPassphrase string) error {
+	plaintext, err := openEnvelope(r, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	defer zero(plaintext)
+
+	env, err := sealEnvelope(plaintext, newPassphrase)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(env)
+}
+
+// RotateEncryptedFile is RotateEncrypted in place, the file is replaced
+// in one go so a crash leaves either the old or the new one behind
+func RotateEncryptedFile(path, oldPassphrase, newPassphrase string) error {
+	b, err := rotateEncryptedFile(path, oldPassphrase, newPassphrase)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// rotateEncryptedFile returns the file at path re-encrypted under the
+// new passphrase
+func rotateEncryptedFile(path, oldPassphrase, newPassphrase string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := openEnvelopeBytes(b, oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(plaintext)
+
+	env, err := sealEnvelope(plaintext, newPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = json.Marshal(env); err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// opensEncryptedFile reports if the file at path opens with passphrase
+func opensEncryptedFile(path, passphrase string) bool {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	plaintext, err := openEnvelopeBytes(b, passphrase)
+	if err != nil {
+		return false
+	}
+	zero(plaintext)
+	return true
+}
+
+func sealEnvelope(plaintext []byte, passphrase string) (*envelope, error) {
+	if passphrase == "" {
+		return nil, ErrPasskeyRequired
+	}
+
+	env := &envelope{envelopeHeader: envelopeHeader{
+		Version: envelopeVersion,
+		KDF:     "argon2id",
+		Params:  envelopeKDF,
+		Salt:    make([]byte, 16),
+		Cipher:  "aes-256-gcm",
+	}}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+
+	aad, err := json.Marshal(&env.envelopeHeader)
+	if err != nil {
+		return nil, err
+	}
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plaintext, aad)
+	return env, nil
+}
+
+func openEnvelope(r io.Reader, passphrase string) ([]byte, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return openEnvelopeBytes(b, passphrase)
+}
+
+func openEnvelopeBytes(b []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPasskeyRequired
+	}
+
+	env := &envelope{}
+	if err := json.Unmarshal(b, env); err != nil {
+		return nil, err
+	}
+	if env.Version != envelopeVersion || env.KDF != "argon2id" || env.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("%w: encrypted account version %d (%s, %s)", ErrUnsupportedVersion, env.Version, env.KDF, env.Cipher)
+	}
+	// don't let a hostile header have us allocate all the memory
+	if env.Params.Time == 0 || env.Params.Time > 16 || env.Params.Memory > 1024*1024 || env.Params.Threads == 0 {
+		return nil, fmt.Errorf("%w: unreasonable kdf parameters", ErrUnsupportedVersion)
+	}
+
+	gcm, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted account")
+	}
+
+	aad, err := json.Marshal(&env.envelopeHeader)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrBadPasskey
+	}
+	return plaintext, nil
+}
+
+func (e *envelope) aead(passphrase string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), e.Salt, e.Params.Time, e.Params.Memory, e.Params.Threads, 32)
+	defer zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// zero scrubs secrets we're done with, it's best effort as the
+// runtime is free to have made copies
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
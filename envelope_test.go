@@ -0,0 +1,68 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoadEncrypted(t *testing.T) {
+	defer func(kdf envelopeKDFParams) { envelopeKDF = kdf }(envelopeKDF)
+	envelopeKDF = envelopeKDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+	account := &SteamGuardAccount{AccountName: "bot", SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=", RevocationCode: "R12345"}
+
+	buf := &bytes.Buffer{}
+	if err := account.SaveEncrypted(buf, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("R12345")) || bytes.Contains(buf.Bytes(), []byte("shared_secret")) {
+		t.Fatalf("account written in the clear %s", buf)
+	}
+
+	if err := (&SteamGuardAccount{}).LoadEncrypted(bytes.NewReader(buf.Bytes()), "wrong"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+
+	tampered := bytes.Replace(buf.Bytes(), []byte(`"time":1`), []byte(`"time":2`), 1)
+	if err := (&SteamGuardAccount{}).LoadEncrypted(bytes.NewReader(tampered), "old"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected an altered header to fail, got %v", err)
+	}
+
+	unsupported := bytes.Replace(buf.Bytes(), []byte(`"version":1`), []byte(`"version":2`), 1)
+	if err := (&SteamGuardAccount{}).LoadEncrypted(bytes.NewReader(unsupported), "old"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bot.enc")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RotateEncryptedFile(path, "wrong", "new"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+	if err := RotateEncryptedFile(path, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	loaded := &SteamGuardAccount{}
+	if err := loaded.LoadEncrypted(file, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.AccountName != "bot" || loaded.RevocationCode != "R12345" {
+		t.Errorf("loaded account mismatched %#v", loaded)
+	}
+}
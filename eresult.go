@@ -0,0 +1,296 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// EResult is the result code steam uses to report the outcome of a
+// request, either in a "status" field or the X-eresult header
+type EResult int
+
+// Known EResults
+const (
+	EResultInvalid                                 EResult = 0
+	EResultOK                                      EResult = 1
+	EResultFail                                    EResult = 2
+	EResultNoConnection                            EResult = 3
+	EResultInvalidPassword                         EResult = 5
+	EResultLoggedInElsewhere                       EResult = 6
+	EResultInvalidProtocolVer                      EResult = 7
+	EResultInvalidParam                            EResult = 8
+	EResultFileNotFound                            EResult = 9
+	EResultBusy                                    EResult = 10
+	EResultInvalidState                            EResult = 11
+	EResultInvalidName                             EResult = 12
+	EResultInvalidEmail                            EResult = 13
+	EResultDuplicateName                           EResult = 14
+	EResultAccessDenied                            EResult = 15
+	EResultTimeout                                 EResult = 16
+	EResultBanned                                  EResult = 17
+	EResultAccountNotFound                         EResult = 18
+	EResultInvalidSteamID                          EResult = 19
+	EResultServiceUnavailable                      EResult = 20
+	EResultNotLoggedOn                             EResult = 21
+	EResultPending                                 EResult = 22
+	EResultEncryptionFailure                       EResult = 23
+	EResultInsufficientPrivilege                   EResult = 24
+	EResultLimitExceeded                           EResult = 25
+	EResultRevoked                                 EResult = 26
+	EResultExpired                                 EResult = 27
+	EResultAlreadyRedeemed                         EResult = 28
+	EResultDuplicateRequest                        EResult = 29
+	EResultAlreadyOwned                            EResult = 30
+	EResultIPNotFound                              EResult = 31
+	EResultPersistFailed                           EResult = 32
+	EResultLockingFailed                           EResult = 33
+	EResultLogonSessionReplaced                    EResult = 34
+	EResultConnectFailed                           EResult = 35
+	EResultHandshakeFailed                         EResult = 36
+	EResultIOFailure                               EResult = 37
+	EResultRemoteDisconnect                        EResult = 38
+	EResultShoppingCartNotFound                    EResult = 39
+	EResultBlocked                                 EResult = 40
+	EResultIgnored                                 EResult = 41
+	EResultNoMatch                                 EResult = 42
+	EResultAccountDisabled                         EResult = 43
+	EResultServiceReadOnly                         EResult = 44
+	EResultAccountNotFeatured                      EResult = 45
+	EResultAdministratorOK                         EResult = 46
+	EResultContentVersion                          EResult = 47
+	EResultTryAnotherCM                            EResult = 48
+	EResultPasswordRequiredToKickSession           EResult = 49
+	EResultAlreadyLoggedInElsewhere                EResult = 50
+	EResultSuspended                               EResult = 51
+	EResultCancelled                               EResult = 52
+	EResultDataCorruption                          EResult = 53
+	EResultDiskFull                                EResult = 54
+	EResultRemoteCallFailed                        EResult = 55
+	EResultPasswordUnset                           EResult = 56
+	EResultExternalAccountUnlinked                 EResult = 57
+	EResultPSNTicketInvalid                        EResult = 58
+	EResultExternalAccountAlreadyLinked            EResult = 59
+	EResultRemoteFileConflict                      EResult = 60
+	EResultIllegalPassword                         EResult = 61
+	EResultSameAsPreviousValue                     EResult = 62
+	EResultAccountLogonDenied                      EResult = 63
+	EResultCannotUseOldPassword                    EResult = 64
+	EResultInvalidLoginAuthCode                    EResult = 65
+	EResultAccountLogonDeniedNoMail                EResult = 66
+	EResultHardwareNotCapableOfIPT                 EResult = 67
+	EResultIPTInitError                            EResult = 68
+	EResultParentalControlRestricted               EResult = 69
+	EResultFacebookQueryError                      EResult = 70
+	EResultExpiredLoginAuthCode                    EResult = 71
+	EResultIPLoginRestrictionFailed                EResult = 72
+	EResultAccountLockedDown                       EResult = 73
+	EResultAccountLogonDeniedVerifiedEmailRequired EResult = 74
+	EResultNoMatchingURL                           EResult = 75
+	EResultBadResponse                             EResult = 76
+	EResultRequirePasswordReEntry                  EResult = 77
+	EResultValueOutOfRange                         EResult = 78
+	EResultUnexpectedError                         EResult = 79
+	EResultDisabled                                EResult = 80
+	EResultInvalidCEGSubmission                    EResult = 81
+	EResultRestrictedDevice                        EResult = 82
+	EResultRegionLocked                            EResult = 83
+	EResultRateLimitExceeded                       EResult = 84
+	EResultAccountLoginDeniedNeedTwoFactor         EResult = 85
+	EResultItemDeleted                             EResult = 86
+	EResultAccountLoginDeniedThrottle              EResult = 87
+	EResultTwoFactorCodeMismatch                   EResult = 88
+	EResultTwoFactorActivationCodeMismatch         EResult = 89
+	EResultAccountAssociatedToMultiplePartners     EResult = 90
+	EResultNotModified                             EResult = 91
+	EResultNoMobileDevice                          EResult = 92
+	EResultTimeNotSynced                           EResult = 93
+	EResultSMSCodeFailed                           EResult = 94
+	EResultAccountLimitExceeded                    EResult = 95
+	EResultAccountActivityLimitExceeded            EResult = 96
+	EResultPhoneActivityLimitExceeded              EResult = 97
+	EResultRefundToWallet                          EResult = 98
+	EResultEmailSendFailure                        EResult = 99
+	EResultNotSettled                              EResult = 100
+	EResultNeedCaptcha                             EResult = 101
+	EResultGSLTDenied                              EResult = 102
+	EResultGSOwnerDenied                           EResult = 103
+	EResultInvalidItemType                         EResult = 104
+	EResultIPBanned                                EResult = 105
+	EResultGSLTExpired                             EResult = 106
+	EResultInsufficientFunds                       EResult = 107
+	EResultTooManyPending                          EResult = 108
+	EResultNoSiteLicensesFound                     EResult = 109
+	EResultWGNetworkSendExceeded                   EResult = 110
+	EResultAccountNotFriends                       EResult = 111
+	EResultLimitedUserAccount                      EResult = 112
+	EResultCantRemoveItem                          EResult = 113
+	EResultAccountDeleted                          EResult = 114
+	EResultExistingUserCancelledLicense            EResult = 115
+	EResultCommunityCooldown                       EResult = 116
+	EResultNoLauncherSpecified                     EResult = 117
+	EResultMustAgreeToSSA                          EResult = 118
+	EResultLauncherMigrated                        EResult = 119
+	EResultSteamRealmMismatch                      EResult = 120
+	EResultInvalidSignature                        EResult = 121
+	EResultParseFailure                            EResult = 122
+	EResultNoVerifiedPhone                         EResult = 123
+	EResultInsufficientBattery                     EResult = 124
+	EResultChargerRequired                         EResult = 125
+	EResultCachedCredentialInvalid                 EResult = 126
+	EResultPhoneNumberIsVOIP                       EResult = 127
+	EResultNotSupported                            EResult = 128
+	EResultFamilySizeLimitExceeded                 EResult = 129
+)
+
+var eResults = map[EResult]string{
+	EResultInvalid:                                 "invalid",
+	EResultOK:                                      "ok",
+	EResultFail:                                    "fail",
+	EResultNoConnection:                            "no connection",
+	EResultInvalidPassword:                         "invalid password",
+	EResultLoggedInElsewhere:                       "logged in elsewhere",
+	EResultInvalidProtocolVer:                      "invalid protocol ver",
+	EResultInvalidParam:                            "invalid param",
+	EResultFileNotFound:                            "file not found",
+	EResultBusy:                                    "busy",
+	EResultInvalidState:                            "invalid state",
+	EResultInvalidName:                             "invalid name",
+	EResultInvalidEmail:                            "invalid email",
+	EResultDuplicateName:                           "duplicate name",
+	EResultAccessDenied:                            "access denied",
+	EResultTimeout:                                 "timeout",
+	EResultBanned:                                  "banned",
+	EResultAccountNotFound:                         "account not found",
+	EResultInvalidSteamID:                          "invalid steam ID",
+	EResultServiceUnavailable:                      "service unavailable",
+	EResultNotLoggedOn:                             "not logged on",
+	EResultPending:                                 "pending",
+	EResultEncryptionFailure:                       "encryption failure",
+	EResultInsufficientPrivilege:                   "insufficient privilege",
+	EResultLimitExceeded:                           "limit exceeded",
+	EResultRevoked:                                 "revoked",
+	EResultExpired:                                 "expired",
+	EResultAlreadyRedeemed:                         "already redeemed",
+	EResultDuplicateRequest:                        "duplicate request",
+	EResultAlreadyOwned:                            "already owned",
+	EResultIPNotFound:                              "IP not found",
+	EResultPersistFailed:                           "persist failed",
+	EResultLockingFailed:                           "locking failed",
+	EResultLogonSessionReplaced:                    "logon session replaced",
+	EResultConnectFailed:                           "connect failed",
+	EResultHandshakeFailed:                         "handshake failed",
+	EResultIOFailure:                               "IO failure",
+	EResultRemoteDisconnect:                        "remote disconnect",
+	EResultShoppingCartNotFound:                    "shopping cart not found",
+	EResultBlocked:                                 "blocked",
+	EResultIgnored:                                 "ignored",
+	EResultNoMatch:                                 "no match",
+	EResultAccountDisabled:                         "account disabled",
+	EResultServiceReadOnly:                         "service read only",
+	EResultAccountNotFeatured:                      "account not featured",
+	EResultAdministratorOK:                         "administrator ok",
+	EResultContentVersion:                          "content version",
+	EResultTryAnotherCM:                            "try another CM",
+	EResultPasswordRequiredToKickSession:           "password required to kick session",
+	EResultAlreadyLoggedInElsewhere:                "already logged in elsewhere",
+	EResultSuspended:                               "suspended",
+	EResultCancelled:                               "cancelled",
+	EResultDataCorruption:                          "data corruption",
+	EResultDiskFull:                                "disk full",
+	EResultRemoteCallFailed:                        "remote call failed",
+	EResultPasswordUnset:                           "password unset",
+	EResultExternalAccountUnlinked:                 "external account unlinked",
+	EResultPSNTicketInvalid:                        "PSN ticket invalid",
+	EResultExternalAccountAlreadyLinked:            "external account already linked",
+	EResultRemoteFileConflict:                      "remote file conflict",
+	EResultIllegalPassword:                         "illegal password",
+	EResultSameAsPreviousValue:                     "same as previous value",
+	EResultAccountLogonDenied:                      "account logon denied",
+	EResultCannotUseOldPassword:                    "cannot use old password",
+	EResultInvalidLoginAuthCode:                    "invalid login auth code",
+	EResultAccountLogonDeniedNoMail:                "account logon denied no mail",
+	EResultHardwareNotCapableOfIPT:                 "hardware not capable of IPT",
+	EResultIPTInitError:                            "IPT init error",
+	EResultParentalControlRestricted:               "parental control restricted",
+	EResultFacebookQueryError:                      "facebook query error",
+	EResultExpiredLoginAuthCode:                    "expired login auth code",
+	EResultIPLoginRestrictionFailed:                "IP login restriction failed",
+	EResultAccountLockedDown:                       "account locked down",
+	EResultAccountLogonDeniedVerifiedEmailRequired: "account logon denied verified email required",
+	EResultNoMatchingURL:                           "no matching URL",
+	EResultBadResponse:                             "bad response",
+	EResultRequirePasswordReEntry:                  "require password re-entry",
+	EResultValueOutOfRange:                         "value out of range",
+	EResultUnexpectedError:                         "unexpected error",
+	EResultDisabled:                                "disabled",
+	EResultInvalidCEGSubmission:                    "invalid CEG submission",
+	EResultRestrictedDevice:                        "restricted device",
+	EResultRegionLocked:                            "region locked",
+	EResultRateLimitExceeded:                       "rate limit exceeded",
+	EResultAccountLoginDeniedNeedTwoFactor:         "account login denied need two factor",
+	EResultItemDeleted:                             "item deleted",
+	EResultAccountLoginDeniedThrottle:              "account login denied throttle",
+	EResultTwoFactorCodeMismatch:                   "two factor code mismatch",
+	EResultTwoFactorActivationCodeMismatch:         "two factor activation code mismatch",
+	EResultAccountAssociatedToMultiplePartners:     "account associated to multiple partners",
+	EResultNotModified:                             "not modified",
+	EResultNoMobileDevice:                          "no mobile device",
+	EResultTimeNotSynced:                           "time not synced",
+	EResultSMSCodeFailed:                           "SMS code failed",
+	EResultAccountLimitExceeded:                    "account limit exceeded",
+	EResultAccountActivityLimitExceeded:            "account activity limit exceeded",
+	EResultPhoneActivityLimitExceeded:              "phone activity limit exceeded",
+	EResultRefundToWallet:                          "refund to wallet",
+	EResultEmailSendFailure:                        "email send failure",
+	EResultNotSettled:                              "not settled",
+	EResultNeedCaptcha:                             "need captcha",
+	EResultGSLTDenied:                              "GSLT denied",
+	EResultGSOwnerDenied:                           "GS owner denied",
+	EResultInvalidItemType:                         "invalid item type",
+	EResultIPBanned:                                "IP banned",
+	EResultGSLTExpired:                             "GSLT expired",
+	EResultInsufficientFunds:                       "insufficient funds",
+	EResultTooManyPending:                          "too many pending",
+	EResultNoSiteLicensesFound:                     "no site licenses found",
+	EResultWGNetworkSendExceeded:                   "WG network send exceeded",
+	EResultAccountNotFriends:                       "account not friends",
+	EResultLimitedUserAccount:                      "limited user account",
+	EResultCantRemoveItem:                          "can't remove item",
+	EResultAccountDeleted:                          "account deleted",
+	EResultExistingUserCancelledLicense:            "existing user cancelled license",
+	EResultCommunityCooldown:                       "community cooldown",
+	EResultNoLauncherSpecified:                     "no launcher specified",
+	EResultMustAgreeToSSA:                          "must agree to SSA",
+	EResultLauncherMigrated:                        "launcher migrated",
+	EResultSteamRealmMismatch:                      "steam realm mismatch",
+	EResultInvalidSignature:                        "invalid signature",
+	EResultParseFailure:                            "parse failure",
+	EResultNoVerifiedPhone:                         "no verified phone",
+	EResultInsufficientBattery:                     "insufficient battery",
+	EResultChargerRequired:                         "charger required",
+	EResultCachedCredentialInvalid:                 "cached credential invalid",
+	EResultPhoneNumberIsVOIP:                       "phone number is VOIP",
+	EResultNotSupported:                            "not supported",
+	EResultFamilySizeLimitExceeded:                 "family size limit exceeded",
+}
+
+func (e EResult) String() string {
+	if s, ok := eResults[e]; ok {
+		return s
+	}
+	return "unknown eresult " + strconv.Itoa(int(e))
+}
+
+// responseEResult returns the EResult steam put in the X-eresult header
+// of the response, EResultInvalid if there wasn't one
+func responseEResult(r *http.Response) EResult {
+	i, err := strconv.Atoi(r.Header.Get("X-eresult"))
+	if err != nil {
+		return EResultInvalid
+	}
+	return EResult(i)
+}
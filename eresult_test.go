@@ -0,0 +1,50 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEResultString(t *testing.T) {
+	cases := []struct {
+		input  EResult
+		output string
+	}{
+		{EResultOK, "ok"},
+		{EResultTwoFactorCodeMismatch, "two factor code mismatch"},
+		{EResultTwoFactorActivationCodeMismatch, "two factor activation code mismatch"},
+		{EResult(4), "unknown eresult 4"},
+	}
+
+	for _, test := range cases {
+		if test.input.String() != test.output {
+			t.Errorf("string mismatched `%s` <> `%s`", test.input.String(), test.output)
+		}
+	}
+}
+
+func TestEResultHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-eresult", r.URL.Query().Get("eresult"))
+	}))
+	defer server.Close()
+
+	if _, err := SteamWeb().Get(server.URL + "/?eresult=1").Do(); err != nil {
+		t.Errorf("unexpected error for eresult ok: %s", err)
+	}
+
+	_, err := SteamWeb().Get(server.URL + "/?eresult=84").Do()
+	var steamErr *SteamError
+	if !errors.As(err, &steamErr) || steamErr.EResult != EResultRateLimitExceeded {
+		t.Fatalf("expected a rate limited SteamError, got %v", err)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("rate limit exceeded should match ErrRateLimited")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by this package, network failures are returned as is
+// so you can tell "network down" from "steam said no" from "our data
+// is corrupt" with errors.Is
+var (
+	ErrSessionExpired        = errors.New("session expired")
+	ErrNoSession             = errors.New("no session")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrInvalidSharedSecret   = errors.New("invalid shared secret")
+	ErrInvalidIdentitySecret = errors.New("invalid identity secret")
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrSteamRejected         = errors.New("steam rejected the request")
+	ErrApprovalNotFound      = errors.New("approval not found")
+	ErrApprovalClosed        = errors.New("approval closed")
+	ErrNotApprover           = errors.New("not an approver")
+	ErrNoApprovers           = errors.New("no approvers")
+	ErrQuorumUnreachable     = errors.New("quorum unreachable")
+	ErrAlreadyDecided        = errors.New("already decided")
+	ErrAuditTampered         = errors.New("audit log tampered with")
+	ErrPasskeyRequired       = errors.New("passkey required")
+	ErrBadPasskey            = errors.New("bad passkey")
+	ErrUnsupportedVersion    = errors.New("unsupported version")
+	ErrInvalidOTPAuthURI     = errors.New("invalid otpauth uri")
+	ErrAccountNotFound       = errors.New("account not found")
+)
+
+// SteamError is returned when steam understood the request but said no,
+// it matches ErrSteamRejected and carries the EResult steam gave if any
+type SteamError struct {
+	Op      string
+	EResult EResult
+	Message string
+}
+
+func (e *SteamError) Error() string {
+	msg := "steam rejected " + e.Op
+	if e.EResult != 0 {
+		msg += fmt.Sprintf(" (%s)", e.EResult)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is allows errors.Is(err, ErrSteamRejected) to match any SteamError,
+// and ErrRateLimited to match one steam rejected for going too fast
+func (e *SteamError) Is(target error) bool {
+	switch target {
+	case ErrSteamRejected:
+		return true
+	case ErrRateLimited:
+		return e.EResult == EResultRateLimitExceeded || e.EResult == EResultAccountLoginDeniedThrottle
+	}
+	return false
+}
+
+// isEResult reports if err is a SteamError with the given EResult
+func isEResult(err error, eresult EResult) bool {
+	var steamErr *SteamError
+	return errors.As(err, &steamErr) && steamErr.EResult == eresult
+}
@@ -127,7 +127,15 @@ func main() {
 		}
 	}
 
-	fmt.Println("Your steamguard code:", account.GenerateSteamGuardCode())
+	code, err := account.GenerateSteamGuardCode()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Your steamguard code:", code)
 
-	fmt.Printf("%#v\n", account.FetchConfirmations)
+	confirmations, err := account.FetchConfirmations()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%#v\n", confirmations)
 }
@@ -1,3 +1,11 @@
 module github.com/freman/go-steamauth
 
-go 1.16
+go 1.17
+
+require (
+	golang.org/x/crypto v0.14.0
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.13.0 // indirect
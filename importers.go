@@ -0,0 +1,233 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImportedAccount is an account recovered from another authenticator's
+// backup, Missing lists the fields (by json name) that weren't in it.
+// Without an identity_secret it can't confirm anything, without a
+// revocation_code it can't be removed and without a device_id or
+// steamid confirmations won't be accepted by steam
+type ImportedAccount struct {
+	Account *SteamGuardAccount
+	Missing []string
+}
+
+// Complete reports if nothing is missing
+func (i *ImportedAccount) Complete() bool {
+	return len(i.Missing) == 0
+}
+
+// steamguardJSON is the steam authenticator data WinAuth and the
+// android app keep, a SteamGuardAccount with the steam id alongside
+type steamguardJSON struct {
+	*SteamGuardAccount
+	SteamID SteamID `json:"steamid"`
+}
+
+func newImportedAccount(account *SteamGuardAccount, steamID SteamID) *ImportedAccount {
+	if steamID != 0 {
+		if account.Session == nil {
+			account.Session = &SessionData{}
+		}
+		if account.Session.SteamID == 0 {
+			account.Session.SteamID = steamID
+		}
+	}
+
+	imported := &ImportedAccount{Account: account}
+	for _, field := range []struct {
+		name    string
+		missing bool
+	}{
+		{"shared_secret", account.SharedSecret == ""},
+		{"identity_secret", account.IdentitySecret == ""},
+		{"revocation_code", account.RevocationCode == ""},
+		{"device_id", account.DeviceID == ""},
+		{"steamid", accountSteamID(account) == 0},
+	} {
+		if field.missing {
+			imported.Missing = append(imported.Missing, field.name)
+		}
+	}
+	return imported
+}
+
+// ImportWinAuth reads an unencrypted WinAuth export (one otpauth:// URI
+// per line), authenticators that aren't steam are skipped. WinAuth puts
+// the steam data and device id in the data and deviceid parameters,
+// lines that can't be read are returned as AccountErrors along with
+// the rest
+func ImportWinAuth(r io.Reader) ([]*ImportedAccount, error) {
+	var imported []*ImportedAccount
+	var errs AccountErrors
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		uri := strings.TrimSpace(scanner.Text())
+		if uri == "" {
+			continue
+		}
+
+		account, err := parseWinAuthURI(uri)
+		if err == errNotSteam {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, &AccountError{Account: "line " + strconv.Itoa(line), Err: err})
+			continue
+		}
+		imported = append(imported, account)
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, err
+	}
+
+	if len(errs) > 0 {
+		return imported, errs
+	}
+	return imported, nil
+}
+
+var errNotSteam = errors.New("not a steam authenticator")
+
+func parseWinAuthURI(uri string) (*ImportedAccount, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOTPAuthURI, err)
+	}
+	query := u.Query()
+	if !strings.EqualFold(query.Get("issuer"), "steam") && !strings.HasPrefix(strings.ToLower(strings.TrimPrefix(u.Path, "/")), "steam:") {
+		return nil, errNotSteam
+	}
+
+	account, err := ParseOTPAuthURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	steamID := SteamID(0)
+	if data := query.Get("data"); data != "" {
+		decoded := steamguardJSON{SteamGuardAccount: account}
+		if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+			return nil, err
+		}
+		steamID = decoded.SteamID
+	}
+	if account.DeviceID == "" {
+		account.DeviceID = query.Get("deviceid")
+	}
+
+	return newImportedAccount(account, steamID), nil
+}
+
+// ImportAndroidSteamguard reads a Steamguard-<steamid> file from the
+// (pre 3.0) steam android app, the device id isn't in it so pass the
+// one from shared_prefs/steam.uuid.xml if you have it
+func ImportAndroidSteamguard(r io.Reader, deviceID string) (*ImportedAccount, error) {
+	decoded := steamguardJSON{SteamGuardAccount: &SteamGuardAccount{}}
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	account := decoded.SteamGuardAccount
+	if account.DeviceID == "" {
+		account.DeviceID = deviceID
+	}
+	return newImportedAccount(account, decoded.SteamID), nil
+}
+
+// ImportAndroidAppDir reads every files/Steamguard-<steamid> in a copy of
+// the steam android app's data directory (com.valvesoftware.android.steam.community)
+// along with the device id from shared_prefs/steam.uuid.xml, files that
+// can't be read are returned as AccountErrors along with the rest
+func ImportAndroidAppDir(dir string) ([]*ImportedAccount, error) {
+	deviceID, err := readAndroidDeviceID(filepath.Join(dir, "shared_prefs", "steam.uuid.xml"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "files", "Steamguard-*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []*ImportedAccount
+	var errs AccountErrors
+	for _, path := range paths {
+		account, err := importAndroidSteamguardFile(path, deviceID)
+		if err != nil {
+			errs = append(errs, &AccountError{Account: filepath.Base(path), Err: err})
+			continue
+		}
+		imported = append(imported, account)
+	}
+
+	if len(errs) > 0 {
+		return imported, errs
+	}
+	return imported, nil
+}
+
+func importAndroidSteamguardFile(path, deviceID string) (*ImportedAccount, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	imported, err := ImportAndroidSteamguard(file, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file is named for the steam id should it be missing from the json
+	if accountSteamID(imported.Account) == 0 {
+		if steamID, err := strconv.ParseUint(strings.TrimPrefix(filepath.Base(path), "Steamguard-"), 10, 64); err == nil {
+			imported = newImportedAccount(imported.Account, SteamID(steamID))
+		}
+	}
+	return imported, nil
+}
+
+// readAndroidDeviceID pulls the uuidKey out of steam.uuid.xml
+func readAndroidDeviceID(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	prefs := struct {
+		Strings []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"string"`
+	}{}
+	if err := xml.Unmarshal(b, &prefs); err != nil {
+		return "", err
+	}
+
+	for _, s := range prefs.Strings {
+		if s.Name == "uuidKey" {
+			return strings.TrimSpace(s.Value), nil
+		}
+	}
+	return "", nil
+}
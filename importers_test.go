@@ -0,0 +1,75 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportWinAuth(t *testing.T) {
+	data := url.QueryEscape(`{"shared_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","serial_number":"123","revocation_code":"R12345","identity_secret":"aWRlbnRpdHk=","account_name":"full","steamid":"76561198263585543","status":1}`)
+	export := strings.Join([]string{
+		"otpauth://totp/Steam:full?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU&digits=5&issuer=Steam&deviceid=android%3Aabc&data=" + data,
+		"otpauth://totp/Google:someone?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU&issuer=Google",
+		"",
+		"otpauth://totp/Steam:codesonly?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU&digits=5&issuer=Steam",
+		"otpauth://totp/Steam:broken?secret=!!!&issuer=Steam",
+	}, "\n")
+
+	imported, err := ImportWinAuth(strings.NewReader(export))
+	var errs AccountErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Account != "line 5" {
+		t.Errorf("expected line 5 to fail, got %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("expected 2 steam accounts, got %d", len(imported))
+	}
+
+	full := imported[0]
+	if !full.Complete() || full.Account.DeviceID != "android:abc" || full.Account.RevocationCode != "R12345" || full.Account.Session.SteamID != 76561198263585543 {
+		t.Errorf("full account mismatched %v %#v", full.Missing, full.Account)
+	}
+
+	codesOnly := imported[1]
+	if strings.Join(codesOnly.Missing, ",") != "identity_secret,revocation_code,device_id,steamid" || codesOnly.Account.SharedSecret != "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=" {
+		t.Errorf("codes only account mismatched %v %#v", codesOnly.Missing, codesOnly.Account)
+	}
+}
+
+func TestImportAndroidAppDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "files"), 0700)
+	os.MkdirAll(filepath.Join(dir, "shared_prefs"), 0700)
+
+	for name, data := range map[string]string{
+		"shared_prefs/steam.uuid.xml":        `<?xml version='1.0' encoding='utf-8' standalone='yes' ?><map><string name="uuidKey">android:1234</string></map>`,
+		"files/Steamguard-76561198263585543": `{"shared_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","serial_number":"123","revocation_code":"R12345","uri":"otpauth://totp/Steam:droid?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU&issuer=Steam","server_time":"1469115000","account_name":"droid","token_gid":"abc","identity_secret":"aWRlbnRpdHk=","secret_1":"c2VjcmV0","status":1,"steamguard_scheme":"2"}`,
+		"files/Steamguard-2":                 `not json`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imported, err := ImportAndroidAppDir(dir)
+	var errs AccountErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Account != "Steamguard-2" {
+		t.Errorf("expected Steamguard-2 to fail, got %v", err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(imported))
+	}
+
+	account := imported[0].Account
+	if !imported[0].Complete() || account.DeviceID != "android:1234" || account.Session.SteamID != 76561198263585543 || account.AccountName != "droid" {
+		t.Errorf("account mismatched %v %#v", imported[0].Missing, account)
+	}
+}
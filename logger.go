@@ -14,67 +14,80 @@ type logLogger interface {
 	Output(calldepth int, s string) error
 }
 
-var (
-	globalLogger     logLogger
-	wantLogRequests  bool
-	wantLogResponses bool
-	wantLogCookies   bool
-)
+// SetLogger to be used for logging by the DefaultClient
+func SetLogger(logger logLogger) {
+	DefaultClient.SetLogger(logger)
+}
+
+// LogRequests enable/disable for the DefaultClient
+func LogRequests(doit bool) {
+	DefaultClient.LogRequests(doit)
+}
+
+// LogResponses enable/disable for the DefaultClient
+func LogResponses(doit bool) {
+	DefaultClient.LogResponses(doit)
+}
+
+// LogCookies enable/disable for the DefaultClient
+func LogCookies(doit bool) {
+	DefaultClient.LogCookies(doit)
+}
 
 // SetLogger to be used for logging
-func SetLogger(logger logLogger) {
-	globalLogger = logger
+func (c *Client) SetLogger(logger logLogger) {
+	c.logger = logger
 }
 
 // LogRequests enable/disable
-func LogRequests(doit bool) {
-	wantLogRequests = doit
+func (c *Client) LogRequests(doit bool) {
+	c.wantLogRequests = doit
 }
 
 // LogResponses enable/disable
-func LogResponses(doit bool) {
-	wantLogResponses = doit
+func (c *Client) LogResponses(doit bool) {
+	c.wantLogResponses = doit
 }
 
 // LogCookies enable/disable
-func LogCookies(doit bool) {
-	wantLogCookies = doit
+func (c *Client) LogCookies(doit bool) {
+	c.wantLogCookies = doit
 }
 
-func log(v ...interface{}) {
-	if globalLogger != nil {
-		globalLogger.Output(2, fmt.Sprint(v...))
+func (c *Client) log(v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Output(2, fmt.Sprint(v...))
 	}
 }
 
-func logln(v ...interface{}) {
-	if globalLogger != nil {
-		globalLogger.Output(2, fmt.Sprintln(v...))
+func (c *Client) logln(v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Output(2, fmt.Sprintln(v...))
 	}
 }
 
-func logf(format string, v ...interface{}) {
-	if globalLogger != nil {
-		globalLogger.Output(2, fmt.Sprintf(format, v...))
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Output(2, fmt.Sprintf(format, v...))
 	}
 }
 
-func logRequest(r *http.Request) {
-	if wantLogRequests && globalLogger != nil {
+func (c *Client) logRequest(r *http.Request) {
+	if c.wantLogRequests && c.logger != nil {
 		dump, _ := httputil.DumpRequestOut(r, true)
-		globalLogger.Output(2, string(dump))
+		c.logger.Output(2, string(dump))
 	}
 }
 
-func logResponse(r *http.Response) {
-	if wantLogResponses && globalLogger != nil {
+func (c *Client) logResponse(r *http.Response) {
+	if c.wantLogResponses && c.logger != nil && r != nil {
 		dump, _ := httputil.DumpResponse(r, true)
-		globalLogger.Output(2, string(dump))
+		c.logger.Output(2, string(dump))
 	}
 }
 
-func logCookies(s *steamWeb, r *http.Request) {
-	if wantLogCookies && globalLogger != nil && s.Jar != nil {
+func (c *Client) logCookies(s *steamWeb, r *http.Request) {
+	if c.wantLogCookies && c.logger != nil && s.Jar != nil {
 		output := []string{fmt.Sprintf("cookies for %s", r.URL)}
 		for _, cookie := range s.Jar.Cookies(r.URL) {
 			output = append(output, fmt.Sprintf("%-25s : %s", cookie.Name, cookie.Value))
@@ -5,6 +5,7 @@
 package steamauth
 
 import (
+	"net/url"
 	"strconv"
 	"time"
 )
@@ -51,30 +52,44 @@ func (c *CaptchaGID) String() string {
 
 // URL returns a fully qualified URL (string) poiting to the captcha image
 func (c *CaptchaGID) URL() string {
+	return c.urlFor(APIEndpoints.CommunityBase)
+}
+
+func (c *CaptchaGID) urlFor(communityBase *url.URL) string {
 	if *c == "" || *c == "-1" {
 		return ""
 	}
-	return APIEndpoints.CommunityBase.String() + "/public/captcha.php?gid=" + c.String()
+	return communityBase.String() + "/public/captcha.php?gid=" + c.String()
 }
 
-type seconds struct {
-	time.Duration
+type timestamp struct {
+	time.Time
 }
 
-func (s *seconds) UnmarshalJSON(b []byte) error {
-	i, err := unmarshalStringyInt(b)
-	if err != nil {
-		return err
+// MarshalJSON writes the unix time as a number like steam (and
+// Steam Desktop Authenticator) do, the zero time is written as 0
+func (t timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
 	}
-	s.Duration = time.Duration(i) * time.Second
-	return nil
-}
-
-type timestamp struct {
-	time.Time
+	return []byte(strconv.FormatInt(t.Unix(), 10)), nil
 }
 
 func (t *timestamp) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if b[0] != '"' {
+		i, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return err
+		}
+		if i != 0 {
+			t.Time = time.Unix(i, 0)
+		}
+		return nil
+	}
+
 	s, err := unmarshalStringyValue(b)
 	if err != nil {
 		return err
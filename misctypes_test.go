@@ -48,3 +48,23 @@ func TestCaptchaGID(t *testing.T) {
 		}
 	}
 }
+
+func TestTimestamp(t *testing.T) {
+	for _, input := range []string{`"1469115000"`, `1469115000`, `"2016-07-21T15:30:00Z"`} {
+		var ts timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err != nil {
+			t.Fatal(err)
+		}
+		if ts.Unix() != 1469115000 {
+			t.Errorf("mismatched %s <> %d", input, ts.Unix())
+		}
+		if b, _ := json.Marshal(ts); string(b) != "1469115000" {
+			t.Errorf("expected to marshal as a number, got %s", b)
+		}
+	}
+
+	var ts timestamp
+	if b, _ := json.Marshal(ts); string(b) != "0" {
+		t.Errorf("expected zero to marshal as 0, got %s", b)
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default templates used to build notification messages, they're
+// executed with the Notification
+const (
+	DefaultNotificationSubject = `{{.Account}}: {{if .Confirmation}}new {{.Confirmation.Type}} confirmation{{else}}{{.Event}}{{end}}`
+	DefaultNotificationBody    = `{{if .Confirmation}}{{.Confirmation.Headline}}{{range .Confirmation.Summary}}
+{{.}}{{end}}{{else}}{{.Event}} on {{.Account}} ({{.SteamID}}){{end}}`
+)
+
+// NotificationEvent is what a Notification is about
+type NotificationEvent string
+
+// Various NotificationEvents
+const (
+	NotifyNewConfirmation      NotificationEvent = "new confirmation"
+	NotifyAuthenticatorAdded   NotificationEvent = "authenticator added"
+	NotifyAuthenticatorRemoved NotificationEvent = "authenticator removed"
+)
+
+// Notification is sent to a Notifier, Confirmation is only set
+// for NotifyNewConfirmation
+type Notification struct {
+	Event        NotificationEvent `json:"event"`
+	Time         time.Time         `json:"time"`
+	Account      string            `json:"account"`
+	SteamID      SteamID           `json:"steamid"`
+	Confirmation *Confirmation     `json:"confirmation,omitempty"`
+}
+
+// Notifier tells someone about a notification, set one on Client.Notifier
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// MultiNotifier sends notifications to all of its Notifiers, every
+// one is tried and the first error is returned
+type MultiNotifier []Notifier
+
+// Notify all the notifiers
+func (m MultiNotifier) Notify(ctx context.Context, n *Notification) error {
+	var firstErr error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Render executes the template text with the notification
+func (n *Notification) Render(text string) (string, error) {
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, n); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// webhookHTTPClient is used by WebhookNotifiers without a HTTPClient
+var webhookHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// notify sends the notification if the client has a notifier
+func (c *Client) notify(s *SteamGuardAccount, event NotificationEvent, conf *Confirmation) {
+	if c.Notifier == nil {
+		return
+	}
+	c.sendNotifications(newNotification(s, event, conf))
+}
+
+func newNotification(s *SteamGuardAccount, event NotificationEvent, conf *Confirmation) *Notification {
+	n := &Notification{
+		Event:        event,
+		Time:         time.Now(),
+		Account:      s.AccountName,
+		Confirmation: conf,
+	}
+	if s.Session != nil {
+		n.SteamID = s.Session.SteamID
+	}
+	return n
+}
+
+// sendNotifications hands the notifications to the notifier in the
+// background, in order, so a slow webhook or mail server doesn't hold
+// up whoever's talking to steam. The notifier gets NotifyTimeout for
+// all of them and one that fails is only logged
+func (c *Client) sendNotifications(notifications ...*Notification) {
+	timeout := c.NotifyTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	c.notifyWG.Add(1)
+	go func() {
+		defer c.notifyWG.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		for _, n := range notifications {
+			if err := c.Notifier.Notify(ctx, n); err != nil {
+				c.logf("notify: unable to send %s for %s: %s", n.Event, n.Account, err)
+			}
+		}
+	}()
+}
+
+// FlushNotifications waits for notifications still being sent in the
+// background, call it before exiting so they aren't lost. It returns
+// ctx.Err() if ctx is done first
+func (c *Client) FlushNotifications(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.notifyWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notifyConfirmations sends NotifyNewConfirmation for confirmations
+// this client hasn't seen on the account before
+func (c *Client) notifyConfirmations(s *SteamGuardAccount, confs []*Confirmation) {
+	if c.Notifier == nil || s.Session == nil {
+		return
+	}
+
+	c.notifyMu.Lock()
+	if c.notified == nil {
+		c.notified = map[SteamID]map[string]bool{}
+	}
+	seen := c.notified[s.Session.SteamID]
+	current := make(map[string]bool, len(confs))
+	var fresh []*Confirmation
+	for _, conf := range confs {
+		current[conf.ConfirmationID] = true
+		if !seen[conf.ConfirmationID] {
+			fresh = append(fresh, conf)
+		}
+	}
+	c.notified[s.Session.SteamID] = current
+	c.notifyMu.Unlock()
+
+	if len(fresh) == 0 {
+		return
+	}
+	notifications := make([]*Notification, len(fresh))
+	for i, conf := range fresh {
+		notifications[i] = newNotification(s, NotifyNewConfirmation, conf)
+	}
+	c.sendNotifications(notifications...)
+}
+
+// WebhookNotifier POSTs notifications as json, the body is signed with
+// a HMAC-SHA256 of the secret in the X-Steamauth-Signature header
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	// Template builds the message included in the payload,
+	// defaults to DefaultNotificationBody
+	Template string
+	// HTTPClient defaults to one that gives up after 30 seconds
+	HTTPClient *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to url signed with secret
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, Template: DefaultNotificationBody}
+}
+
+type webhookPayload struct {
+	*Notification
+	Message string `json:"message"`
+}
+
+// Notify posts the notification
+func (w *WebhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	message, err := n.Render(iif(w.Template != "", w.Template, DefaultNotificationBody))
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&webhookPayload{Notification: n, Message: message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Steamauth-Signature", "sha256="+signWebhook(w.Secret, body))
+
+	client := w.HTTPClient
+	if client == nil {
+		client = webhookHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// VerifyWebhookSignature reports if signature (the X-Steamauth-Signature
+// header) is valid for the body, for the receiving end of a WebhookNotifier
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	expected := "sha256=" + signWebhook(secret, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SMTPNotifier emails notifications
+type SMTPNotifier struct {
+	// Addr of the mail server, host:port
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+	// Subject and Body templates, default to DefaultNotificationSubject
+	// and DefaultNotificationBody
+	Subject string
+	Body    string
+
+	sendMail func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPNotifier returns a notifier sending mail through the server at addr
+func NewSMTPNotifier(addr string, auth smtp.Auth, from string, to ...string) *SMTPNotifier {
+	return &SMTPNotifier{
+		Addr:    addr,
+		Auth:    auth,
+		From:    from,
+		To:      to,
+		Subject: DefaultNotificationSubject,
+		Body:    DefaultNotificationBody,
+	}
+}
+
+// Notify sends the email, giving up when ctx is done
+func (m *SMTPNotifier) Notify(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	subject, err := n.Render(iif(m.Subject != "", m.Subject, DefaultNotificationSubject))
+	if err != nil {
+		return err
+	}
+	body, err := n.Render(iif(m.Body != "", m.Body, DefaultNotificationBody))
+	if err != nil {
+		return err
+	}
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", strings.NewReplacer("\r", "", "\n", " ").Replace(subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	msg.WriteString("\r\n")
+
+	sendMail := m.sendMail
+	if sendMail == nil {
+		sendMail = sendMailContext
+	}
+	return sendMail(ctx, m.Addr, m.Auth, m.From, m.To, msg.Bytes())
+}
+
+// sendMailContext is smtp.SendMail with the connection bound to ctx,
+// net/smtp has no way of being cancelled itself
+func sendMailContext(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(a); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
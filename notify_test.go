@@ -0,0 +1,186 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingNotifier []*Notification
+
+func (r *recordingNotifier) Notify(ctx context.Context, n *Notification) error {
+	*r = append(*r, n)
+	return nil
+}
+
+func TestFetchConfirmationsNotifies(t *testing.T) {
+	pages := []string{
+		`{"success":true,"conf":[{"id":"1","nonce":"1"},{"id":"2","nonce":"2"}]}`,
+		`{"success":true,"conf":[{"id":"2","nonce":"2"},{"id":"3","nonce":"3"}]}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(pages[0]))
+		pages = pages[1:]
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	notifier := &recordingNotifier{}
+	account.steamClient().Notifier = notifier
+
+	account.FetchConfirmations()
+	account.steamClient().FlushNotifications(context.Background())
+	account.FetchConfirmations()
+	account.steamClient().FlushNotifications(context.Background())
+
+	ids := []string{}
+	for _, n := range *notifier {
+		if n.Event != NotifyNewConfirmation || n.SteamID != account.Session.SteamID {
+			t.Errorf("notification mismatched %#v", n)
+		}
+		ids = append(ids, n.Confirmation.ConfirmationID)
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Errorf("expected 1, 2 and 3 to be notified once each, got %v", ids)
+	}
+}
+
+type blockingNotifier chan error
+
+func (b blockingNotifier) Notify(ctx context.Context, n *Notification) error {
+	<-ctx.Done()
+	b <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestFetchConfirmationsDoesntWaitForNotifier(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"conf":[{"id":"1","nonce":"1"}]}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	notifier := make(blockingNotifier, 1)
+	account.steamClient().Notifier = notifier
+	account.steamClient().NotifyTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if _, err := account.FetchConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("fetching waited %s on the notifier", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := account.steamClient().FlushNotifications(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected flushing to give up with the context, got %v", err)
+	}
+
+	select {
+	case err := <-notifier:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected the notifier to time out, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifier was never given up on")
+	}
+	if err := account.steamClient().FlushNotifications(context.Background()); err != nil {
+		t.Errorf("expected the notifications to be flushed, got %v", err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !VerifyWebhookSignature("secret", body, r.Header.Get("X-Steamauth-Signature")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	n := &Notification{
+		Event:        NotifyNewConfirmation,
+		Account:      "bot",
+		Confirmation: &Confirmation{Type: ConfirmationTypeTrade, Headline: "partner", Summary: []string{"You will give up 1 item"}},
+	}
+
+	if err := NewWebhookNotifier(server.URL, "wrong").Notify(context.Background(), n); err == nil {
+		t.Error("expected a bad signature to fail")
+	}
+
+	if err := NewWebhookNotifier(server.URL, "secret").Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if payload["event"] != "new confirmation" || payload["account"] != "bot" || payload["message"] != "partner\nYou will give up 1 item" {
+		t.Errorf("payload mismatched %v", payload)
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	var sent string
+	notifier := NewSMTPNotifier("mail:25", nil, "bot@example.com", "ops@example.com")
+	notifier.sendMail = func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = string(msg)
+		return nil
+	}
+
+	err := notifier.Notify(context.Background(), &Notification{
+		Event:        NotifyNewConfirmation,
+		Account:      "bot",
+		Confirmation: &Confirmation{Type: ConfirmationTypeMarketListing, Headline: "Sell 1 hat"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sent, "Subject: bot: new market listing confirmation\r\n") || !strings.HasSuffix(sent, "\r\n\r\nSell 1 hat\r\n") {
+		t.Errorf("message mismatched %q", sent)
+	}
+}
+
+func TestSMTPNotifierTimeout(t *testing.T) {
+	// a mail server that accepts the connection and never says hello
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = NewSMTPNotifier(listener.Addr().String(), nil, "bot@example.com", "ops@example.com").Notify(ctx, &Notification{Event: NotifyAuthenticatorAdded, Account: "bot"})
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("expected sending to a hung server to give up, got %v after %s", err, time.Since(start))
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// OTPAuthURI returns the otpauth:// URI for this account's codes, apps
+// that understand the steam encoder (and the exports below) only get
+// the shared secret so they can show codes but not confirm anything
+func (s *SteamGuardAccount) OTPAuthURI() (string, error) {
+	secret, err := s.base32SharedSecret()
+	if err != nil {
+		return "", err
+	}
+
+	return "otpauth://totp/Steam:" + url.PathEscape(s.AccountName) + "?secret=" + secret + "&issuer=Steam&digits=5&encoder=steam", nil
+}
+
+// ParseOTPAuthURI returns an account with the shared secret and account
+// name from a steam otpauth:// URI, as made by OTPAuthURI
+func ParseOTPAuthURI(uri string) (*SteamGuardAccount, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOTPAuthURI, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		return nil, fmt.Errorf("%w: not an otpauth://totp uri", ErrInvalidOTPAuthURI)
+	}
+
+	query := u.Query()
+	label := strings.TrimPrefix(u.Path, "/")
+	issuer, name := "", label
+	if i := strings.Index(label, ":"); i >= 0 {
+		issuer, name = label[:i], strings.TrimSpace(label[i+1:])
+	}
+	if issuer == "" {
+		issuer = query.Get("issuer")
+	}
+	if !strings.EqualFold(issuer, "steam") && !strings.EqualFold(query.Get("encoder"), "steam") {
+		return nil, fmt.Errorf("%w: not a steam uri", ErrInvalidOTPAuthURI)
+	}
+
+	secret := strings.ToUpper(strings.TrimRight(strings.Replace(query.Get("secret"), " ", "", -1), "="))
+	if secret == "" {
+		return nil, fmt.Errorf("%w: no secret", ErrInvalidOTPAuthURI)
+	}
+	sharedSecret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOTPAuthURI, err)
+	}
+
+	return &SteamGuardAccount{
+		AccountName:  name,
+		SharedSecret: base64.StdEncoding.EncodeToString(sharedSecret),
+	}, nil
+}
+
+// aegisExport is an unencrypted Aegis vault
+type aegisExport struct {
+	Version int `json:"version"`
+	Header  struct {
+		Slots  interface{} `json:"slots"`
+		Params interface{} `json:"params"`
+	} `json:"header"`
+	DB struct {
+		Version int          `json:"version"`
+		Entries []aegisEntry `json:"entries"`
+	} `json:"db"`
+}
+
+type aegisEntry struct {
+	Type     string    `json:"type"`
+	UUID     string    `json:"uuid"`
+	Name     string    `json:"name"`
+	Issuer   string    `json:"issuer"`
+	Note     string    `json:"note"`
+	Favorite bool      `json:"favorite"`
+	Icon     *string   `json:"icon"`
+	Info     aegisInfo `json:"info"`
+}
+
+type aegisInfo struct {
+	Secret string `json:"secret"`
+	Algo   string `json:"algo"`
+	Digits int    `json:"digits"`
+	Period int    `json:"period"`
+}
+
+// ExportAegis writes the accounts as an unencrypted Aegis vault, import
+// it into Aegis and set a password straight away
+func ExportAegis(w io.Writer, accounts ...*SteamGuardAccount) error {
+	export := aegisExport{Version: 1}
+	export.DB.Version = 2
+	export.DB.Entries = make([]aegisEntry, 0, len(accounts))
+
+	for _, account := range accounts {
+		secret, err := account.base32SharedSecret()
+		if err != nil {
+			return fmt.Errorf("%s: %w", account.AccountName, err)
+		}
+		uuid, err := newUUID()
+		if err != nil {
+			return err
+		}
+		export.DB.Entries = append(export.DB.Entries, aegisEntry{
+			Type:   "steam",
+			UUID:   uuid,
+			Name:   account.AccountName,
+			Issuer: "Steam",
+			Info:   aegisInfo{Secret: secret, Algo: "SHA1", Digits: 5, Period: 30},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(&export)
+}
+
+type andOTPEntry struct {
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Label         string   `json:"label"`
+	Digits        int      `json:"digits"`
+	Type          string   `json:"type"`
+	Algorithm     string   `json:"algorithm"`
+	Thumbnail     string   `json:"thumbnail"`
+	LastUsed      int64    `json:"last_used"`
+	UsedFrequency int      `json:"used_frequency"`
+	Period        int      `json:"period"`
+	Tags          []string `json:"tags"`
+}
+
+// ExportAndOTP writes the accounts as an unencrypted andOTP backup
+func ExportAndOTP(w io.Writer, accounts ...*SteamGuardAccount) error {
+	entries := make([]andOTPEntry, 0, len(accounts))
+	for _, account := range accounts {
+		secret, err := account.base32SharedSecret()
+		if err != nil {
+			return fmt.Errorf("%s: %w", account.AccountName, err)
+		}
+		entries = append(entries, andOTPEntry{
+			Secret:    secret,
+			Issuer:    "Steam",
+			Label:     account.AccountName,
+			Digits:    5,
+			Type:      "STEAM",
+			Algorithm: "SHA1",
+			Thumbnail: "Default",
+			Period:    30,
+			Tags:      []string{},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// base32SharedSecret is the shared secret the way otp apps want it
+func (s *SteamGuardAccount) base32SharedSecret() (string, error) {
+	if s.SharedSecret == "" {
+		return "", ErrInvalidSharedSecret
+	}
+	sharedSecret, err := base64.StdEncoding.DecodeString(s.SharedSecret)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSharedSecret, err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sharedSecret), nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestOTPAuthURI(t *testing.T) {
+	account := &SteamGuardAccount{AccountName: "bot one", SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="}
+
+	uri, err := account.OTPAuthURI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "otpauth://totp/Steam:bot%20one?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU&issuer=Steam&digits=5&encoder=steam"; uri != expected {
+		t.Errorf("uri mismatched %s <> %s", uri, expected)
+	}
+
+	parsed, err := ParseOTPAuthURI(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.AccountName != account.AccountName || parsed.SharedSecret != account.SharedSecret {
+		t.Errorf("parsed account mismatched %#v", parsed)
+	}
+
+	for _, uri := range []string{
+		"otpauth://hotp/Steam:bot?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU",
+		"otpauth://totp/Google:bot?secret=OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU",
+		"otpauth://totp/Steam:bot?secret=not!base32",
+	} {
+		if _, err := ParseOTPAuthURI(uri); !errors.Is(err, ErrInvalidOTPAuthURI) {
+			t.Errorf("expected ErrInvalidOTPAuthURI for %s, got %v", uri, err)
+		}
+	}
+}
+
+func TestExportAegisAndOTP(t *testing.T) {
+	account := &SteamGuardAccount{AccountName: "bot", SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="}
+
+	buf := &bytes.Buffer{}
+	if err := ExportAegis(buf, account); err != nil {
+		t.Fatal(err)
+	}
+	aegis := aegisExport{}
+	if err := json.Unmarshal(buf.Bytes(), &aegis); err != nil {
+		t.Fatal(err)
+	}
+	if len(aegis.DB.Entries) != 1 || aegis.DB.Entries[0].Type != "steam" || aegis.DB.Entries[0].Info.Secret != "OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU" || len(aegis.DB.Entries[0].UUID) != 36 {
+		t.Errorf("aegis export mismatched %s", buf)
+	}
+
+	buf.Reset()
+	if err := ExportAndOTP(buf, account); err != nil {
+		t.Fatal(err)
+	}
+	andOTP := []andOTPEntry{}
+	if err := json.Unmarshal(buf.Bytes(), &andOTP); err != nil {
+		t.Fatal(err)
+	}
+	if len(andOTP) != 1 || andOTP[0].Type != "STEAM" || andOTP[0].Label != "bot" || andOTP[0].Secret != "OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU" {
+		t.Errorf("andOTP export mismatched %s", buf)
+	}
+
+	if err := ExportAndOTP(buf, &SteamGuardAccount{AccountName: "broken"}); !errors.Is(err, ErrInvalidSharedSecret) {
+		t.Errorf("expected ErrInvalidSharedSecret, got %v", err)
+	}
+}
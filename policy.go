@@ -0,0 +1,294 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var policyPriceRegex = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+
+// PolicyAction is what a Policy decides to do with a confirmation
+type PolicyAction string
+
+// Various PolicyActions
+const (
+	PolicyAccept PolicyAction = "accept"
+	PolicyReject PolicyAction = "reject"
+	PolicyLeave  PolicyAction = "pending"
+)
+
+// Policy decides what to do with confirmations, the first rule that
+// matches wins and if none do the Default applies
+type Policy struct {
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+	// Default action when no rule matches, defaults to PolicyLeave
+	Default PolicyAction `json:"default" yaml:"default"`
+	// DryRun decides and logs but doesn't accept or reject anything
+	DryRun bool `json:"dry_run" yaml:"dry_run"`
+}
+
+// PolicyRule matches a confirmation when every condition that is set
+// holds, a rule with no conditions matches everything
+type PolicyRule struct {
+	Name   string       `json:"name" yaml:"name"`
+	Action PolicyAction `json:"action" yaml:"action"`
+
+	// Types are confirmation type names, eg "trade" or "market listing"
+	Types []string `json:"types" yaml:"types"`
+	// Partners only matches trades with these SteamIDs, if the partner
+	// can't be found on the details page it doesn't match
+	Partners []SteamID `json:"partners" yaml:"partners"`
+	// MaxItemsGiven only matches trades giving away no more than this,
+	// if the items can't be found on the details page it doesn't match
+	MaxItemsGiven *int `json:"max_items_given" yaml:"max_items_given"`
+	// MaxMarketPrice only matches market listings where the first
+	// amount shown (what you receive) is no more than this, the
+	// currency is ignored
+	MaxMarketPrice *float64 `json:"max_market_price" yaml:"max_market_price"`
+}
+
+// PolicyDecision is the outcome of running a confirmation through
+// a Policy, Rule is the name of the rule that matched or empty if
+// the default applied
+type PolicyDecision struct {
+	Confirmation *Confirmation
+	Action       PolicyAction
+	Rule         string
+	Err          error
+}
+
+// LoadPolicyFile reads a policy from a json or yaml file, which is
+// decided by the extension
+func LoadPolicyFile(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return LoadPolicyYAML(f)
+	}
+	return LoadPolicyJSON(f)
+}
+
+// LoadPolicyJSON reads a json policy from r
+func LoadPolicyJSON(r io.Reader) (*Policy, error) {
+	p := &Policy{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, p.Validate()
+}
+
+// LoadPolicyYAML reads a yaml policy from r
+func LoadPolicyYAML(r io.Reader) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, p.Validate()
+}
+
+// Validate checks the actions and confirmation types in the policy
+func (p *Policy) Validate() error {
+	if !p.Default.valid() && p.Default != "" {
+		return fmt.Errorf("policy: unknown default action %q", p.Default)
+	}
+
+	for i, rule := range p.Rules {
+		if !rule.Action.valid() {
+			return fmt.Errorf("policy: rule %d (%s) has unknown action %q", i, rule.Name, rule.Action)
+		}
+		for _, name := range rule.Types {
+			if !validConfirmationTypeName(name) {
+				return fmt.Errorf("policy: rule %d (%s) has unknown confirmation type %q", i, rule.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decide what to do with the confirmation, details may be nil in
+// which case rules that need them don't match
+func (p *Policy) Decide(conf *Confirmation, details *ConfirmationDetails) PolicyDecision {
+	for i := range p.Rules {
+		if p.Rules[i].matches(conf, details) {
+			return PolicyDecision{Confirmation: conf, Action: p.Rules[i].Action, Rule: p.Rules[i].name(i)}
+		}
+	}
+	return PolicyDecision{Confirmation: conf, Action: iifAction(p.Default != "", p.Default, PolicyLeave)}
+}
+
+// Apply fetches the account's confirmations and accepts or rejects
+// them as the policy decides, unless it's a dry run. Every decision
+// is logged and returned, failures to act are set on the decision
+func (p *Policy) Apply(account *SteamGuardAccount) ([]PolicyDecision, error) {
+	return p.ApplyContext(context.Background(), account)
+}
+
+// ApplyContext is Apply with a context that can cancel any
+// in-flight requests to steam
+func (p *Policy) ApplyContext(ctx context.Context, account *SteamGuardAccount) ([]PolicyDecision, error) {
+	confs, err := account.FetchConfirmationsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := account.steamClient()
+	decisions := make([]PolicyDecision, len(confs))
+	for i, conf := range confs {
+		var details *ConfirmationDetails
+		if p.needsDetails(conf) {
+			if details, err = account.FetchConfirmationDetailsContext(ctx, conf); err != nil {
+				decisions[i] = PolicyDecision{Confirmation: conf, Action: PolicyLeave, Err: err}
+				c.logf("policy: leaving confirmation %s (%s) pending, unable to fetch details: %s", conf.ConfirmationID, conf.Headline, err)
+				continue
+			}
+		}
+
+		decisions[i] = p.Decide(conf, details)
+		c.logf("policy: %s confirmation %s (%s %s) by rule %q%s", decisions[i].Action, conf.ConfirmationID, conf.Type, conf.Headline, decisions[i].Rule, iif(p.DryRun, " (dry run)", ""))
+
+		if p.DryRun {
+			continue
+		}
+
+		switch decisions[i].Action {
+		case PolicyAccept:
+			decisions[i].Err = account.AcceptConfirmationContext(ctx, conf)
+		case PolicyReject:
+			decisions[i].Err = account.RejectConfirmationContext(ctx, conf)
+		}
+
+		if decisions[i].Err != nil {
+			c.logf("policy: unable to %s confirmation %s: %s", decisions[i].Action, conf.ConfirmationID, decisions[i].Err)
+		}
+	}
+
+	return decisions, nil
+}
+
+// needsDetails reports if any rule that could apply to conf looks at
+// the details page
+func (p *Policy) needsDetails(conf *Confirmation) bool {
+	for _, rule := range p.Rules {
+		if rule.matchesType(conf) && (len(rule.Partners) > 0 || rule.MaxItemsGiven != nil || rule.MaxMarketPrice != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PolicyRule) name(i int) string {
+	return iif(r.Name != "", r.Name, "#"+strconv.Itoa(i))
+}
+
+func (r *PolicyRule) matchesType(conf *Confirmation) bool {
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, name := range r.Types {
+		if strings.EqualFold(name, conf.Type.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PolicyRule) matches(conf *Confirmation, details *ConfirmationDetails) bool {
+	if !r.matchesType(conf) {
+		return false
+	}
+
+	if len(r.Partners) == 0 && r.MaxItemsGiven == nil && r.MaxMarketPrice == nil {
+		return true
+	}
+
+	if details == nil {
+		return false
+	}
+
+	// anything the details page didn't give us fails the rule, steam
+	// changing the page shouldn't accept more than it used to
+	if len(r.Partners) > 0 && (details.TradePartnerSteamID == 0 || !containsSteamID(r.Partners, details.TradePartnerSteamID)) {
+		return false
+	}
+
+	if r.MaxItemsGiven != nil && (!details.ItemsFound || len(details.ItemsGiven) > *r.MaxItemsGiven) {
+		return false
+	}
+
+	if r.MaxMarketPrice != nil {
+		price, ok := parseMarketPrice(details.MarketPrice)
+		if !ok || price > *r.MaxMarketPrice {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a PolicyAction) valid() bool {
+	return a == PolicyAccept || a == PolicyReject || a == PolicyLeave
+}
+
+func iifAction(cond bool, a, b PolicyAction) PolicyAction {
+	if cond {
+		return a
+	}
+	return b
+}
+
+func validConfirmationTypeName(name string) bool {
+	for _, s := range confirmationTypes {
+		if strings.EqualFold(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSteamID(ids []SteamID, id SteamID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMarketPrice returns the first amount in the market price text,
+// steam formats it for the wallet currency so a separator followed by
+// two digits at the end is taken as the decimal point and any others
+// as thousands separators
+func parseMarketPrice(s string) (float64, bool) {
+	m := policyPriceRegex.FindString(s)
+	if m == "" {
+		return 0, false
+	}
+
+	whole, fraction := m, ""
+	if i := strings.LastIndexAny(m, ".,"); i >= 0 && len(m)-i == 3 {
+		whole, fraction = m[:i], m[i+1:]
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+
+	price, err := strconv.ParseFloat(whole+"."+fraction, 64)
+	return price, err == nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testPolicyYAML = `
+default: reject
+rules:
+  - name: cheap listings
+    action: accept
+    types: [market listing]
+    max_market_price: 1.5
+  - name: friends gifting
+    action: accept
+    types: [trade]
+    partners: [76561198263585543]
+    max_items_given: 0
+  - name: keep api keys for a human
+    action: pending
+    types: [api key]
+`
+
+func TestPolicyDecide(t *testing.T) {
+	policy, err := LoadPolicyYAML(strings.NewReader(testPolicyYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	friend := SteamID(76561198263585543)
+	tests := []struct {
+		conf    *Confirmation
+		details *ConfirmationDetails
+		action  PolicyAction
+		rule    string
+	}{
+		{&Confirmation{Type: ConfirmationTypeMarketListing}, &ConfirmationDetails{MarketPrice: "You receive: $1.20 ($1.38)"}, PolicyAccept, "cheap listings"},
+		{&Confirmation{Type: ConfirmationTypeMarketListing}, &ConfirmationDetails{MarketPrice: "You receive: 1.234,56€"}, PolicyReject, ""},
+		{&Confirmation{Type: ConfirmationTypeMarketListing}, &ConfirmationDetails{MarketPrice: "You receive: 1,49€"}, PolicyAccept, "cheap listings"},
+		{&Confirmation{Type: ConfirmationTypeMarketListing}, nil, PolicyReject, ""},
+		{&Confirmation{Type: ConfirmationTypeTrade}, &ConfirmationDetails{TradePartnerSteamID: friend, ItemsReceived: []ConfirmationItem{{}}, ItemsFound: true}, PolicyAccept, "friends gifting"},
+		{&Confirmation{Type: ConfirmationTypeTrade}, &ConfirmationDetails{TradePartnerSteamID: friend, ItemsGiven: []ConfirmationItem{{}}, ItemsFound: true}, PolicyReject, ""},
+		{&Confirmation{Type: ConfirmationTypeTrade}, &ConfirmationDetails{TradePartnerSteamID: friend}, PolicyReject, ""},
+		{&Confirmation{Type: ConfirmationTypeTrade}, &ConfirmationDetails{TradePartnerSteamID: 1}, PolicyReject, ""},
+		{&Confirmation{Type: ConfirmationTypeAPIKey}, nil, PolicyLeave, "keep api keys for a human"},
+	}
+
+	for i, test := range tests {
+		decision := policy.Decide(test.conf, test.details)
+		if decision.Action != test.action || decision.Rule != test.rule {
+			t.Errorf("%d: expected %s by %q, got %s by %q", i, test.action, test.rule, decision.Action, decision.Rule)
+		}
+	}
+}
+
+func TestPolicyDecideUnparsedDetails(t *testing.T) {
+	policy, err := LoadPolicyYAML(strings.NewReader(`
+rules:
+  - name: friends gifting
+    action: accept
+    types: [trade]
+    partners: [76561198263585543]
+    max_items_given: 0
+  - name: anyone gifting
+    action: accept
+    types: [trade]
+    max_items_given: 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range []string{
+		``,
+		`<div class="tradeoffer">steam is down for maintenance</div>`,
+		// the item lists renamed
+		`<div data-miniprofile="303319815"></div><div class="tradeoffer_items_mine"><div data-economy-item="classinfo/730/111/0"></div></div><div class="tradeoffer_items_theirs"></div>`,
+		// only the received items found
+		`<div data-miniprofile="303319815"></div><div class="tradeoffer_items secondary"></div>`,
+		// the lists the wrong way round
+		`<div data-miniprofile="303319815"></div><div class="tradeoffer_items secondary"></div><div class="tradeoffer_items primary"><div data-economy-item="classinfo/730/111/0"></div></div>`,
+	} {
+		decision := policy.Decide(&Confirmation{Type: ConfirmationTypeTrade}, parseConfirmationDetails(page))
+		if decision.Action != PolicyLeave {
+			t.Errorf("expected %q to be left, got %s by %q", page, decision.Action, decision.Rule)
+		}
+	}
+
+	// the partner missing only fails the rule that wants one
+	decision := policy.Decide(&Confirmation{Type: ConfirmationTypeTrade}, parseConfirmationDetails(`<div class="tradeoffer_items primary"></div><div class="tradeoffer_items secondary"></div>`))
+	if decision.Action != PolicyAccept || decision.Rule != "anyone gifting" {
+		t.Errorf("expected anyone gifting to accept, got %s by %q", decision.Action, decision.Rule)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	for _, doc := range []string{
+		`{"rules":[{"action":"maybe"}]}`,
+		`{"rules":[{"action":"accept","types":["trades"]}]}`,
+		`{"default":"yes"}`,
+	} {
+		if _, err := LoadPolicyJSON(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected %s to fail validation", doc)
+		}
+	}
+}
+
+func TestPolicyApply(t *testing.T) {
+	answered := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mobileconf/getlist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"conf":[{"type":2,"id":"1","nonce":"1"},{"type":9,"id":"2","nonce":"2"}]}`))
+	})
+	mux.HandleFunc("/mobileconf/details/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"html":"<div data-miniprofile=\"303319815\"></div><div class=\"tradeoffer_items primary\"></div><div class=\"tradeoffer_items secondary\"></div>"}`))
+	})
+	mux.HandleFunc("/mobileconf/ajaxop", func(w http.ResponseWriter, r *http.Request) {
+		answered[r.URL.Query().Get("cid")] = r.URL.Query().Get("op")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	})
+	account, done := newConfirmationTestAccount(t, mux)
+	defer done()
+
+	policy, err := LoadPolicyYAML(strings.NewReader(testPolicyYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy.DryRun = true
+	decisions, err := policy.Apply(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answered) != 0 {
+		t.Errorf("dry run answered confirmations %v", answered)
+	}
+	if len(decisions) != 2 || decisions[0].Action != PolicyAccept || decisions[1].Action != PolicyLeave {
+		t.Fatalf("decisions mismatched %#v", decisions)
+	}
+
+	policy.DryRun = false
+	if _, err := policy.Apply(account); err != nil {
+		t.Fatal(err)
+	}
+	if len(answered) != 1 || answered["1"] != "allow" {
+		t.Errorf("expected only confirmation 1 to be allowed, got %v", answered)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoMarshaler is implemented by the request messages we send to
+// steam services as `input_protobuf_encoded`
+type protoMarshaler interface {
+	marshalProto() []byte
+}
+
+// protoUnmarshaler is implemented by the response messages steam
+// services send back
+type protoUnmarshaler interface {
+	unmarshalProto(b []byte) error
+}
+
+// protoEmpty is for methods that take no arguments, or where all
+// that matters in the response is the X-eresult
+type protoEmpty struct{}
+
+func (m *protoEmpty) marshalProto() []byte {
+	return nil
+}
+
+func (m *protoEmpty) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error { return nil })
+}
+
+// protoWriter is a tiny helper for building protobuf messages by hand,
+// like proto2 optionals zero values are left out
+type protoWriter []byte
+
+func (w *protoWriter) varint(num protowire.Number, v uint64) {
+	if v == 0 {
+		return
+	}
+	*w = protowire.AppendTag(*w, num, protowire.VarintType)
+	*w = protowire.AppendVarint(*w, v)
+}
+
+func (w *protoWriter) bool(num protowire.Number, v bool) {
+	if v {
+		w.varint(num, 1)
+	}
+}
+
+func (w *protoWriter) fixed64(num protowire.Number, v uint64) {
+	if v == 0 {
+		return
+	}
+	*w = protowire.AppendTag(*w, num, protowire.Fixed64Type)
+	*w = protowire.AppendFixed64(*w, v)
+}
+
+func (w *protoWriter) bytes(num protowire.Number, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	*w = protowire.AppendTag(*w, num, protowire.BytesType)
+	*w = protowire.AppendBytes(*w, v)
+}
+
+func (w *protoWriter) string(num protowire.Number, v string) {
+	if v == "" {
+		return
+	}
+	*w = protowire.AppendTag(*w, num, protowire.BytesType)
+	*w = protowire.AppendString(*w, v)
+}
+
+func (w *protoWriter) message(num protowire.Number, m protoMarshaler) {
+	if m == nil {
+		return
+	}
+	*w = protowire.AppendTag(*w, num, protowire.BytesType)
+	*w = protowire.AppendBytes(*w, m.marshalProto())
+}
+
+// protoField is a single decoded field, u holds varint and fixed
+// values and b holds length delimited ones
+type protoField struct {
+	num protowire.Number
+	typ protowire.Type
+	u   uint64
+	b   []byte
+}
+
+func (f protoField) string() string {
+	return string(f.b)
+}
+
+func (f protoField) bytes() []byte {
+	return append([]byte(nil), f.b...)
+}
+
+func (f protoField) bool() bool {
+	return f.u != 0
+}
+
+func (f protoField) int32() int32 {
+	return int32(f.u)
+}
+
+func (f protoField) float32() float32 {
+	return math.Float32frombits(uint32(f.u))
+}
+
+// rangeProtoFields calls fn for every field in the message b
+func rangeProtoFields(b []byte, fn func(f protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.u, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.u = uint64(v)
+		case protowire.Fixed64Type:
+			f.u, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
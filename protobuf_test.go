@@ -0,0 +1,55 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math"
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoRequest returns the decoded `input_protobuf_encoded` of a request
+func protoRequest(r *http.Request) []byte {
+	b, _ := base64.StdEncoding.DecodeString(r.FormValue("input_protobuf_encoded"))
+	return b
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	w := protoWriter{}
+	w.varint(1, 42)
+	w.fixed64(2, 76561198263585543)
+	w.string(3, "hello")
+	w.bytes(4, []byte{1, 2, 3})
+	w.bool(5, true)
+	w.string(6, "")
+	w = protowire.AppendTag(w, 7, protowire.Fixed32Type)
+	w = protowire.AppendFixed32(w, math.Float32bits(1.5))
+
+	seen := map[protowire.Number]protoField{}
+	err := rangeProtoFields(w, func(f protoField) error {
+		seen[f.num] = f
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seen[1].u != 42 || seen[2].u != 76561198263585543 || seen[3].string() != "hello" ||
+		!bytes.Equal(seen[4].bytes(), []byte{1, 2, 3}) || !seen[5].bool() || seen[7].float32() != 1.5 {
+		t.Errorf("mismatched %#v", seen)
+	}
+
+	if _, ok := seen[6]; ok {
+		t.Error("empty string should have been left out")
+	}
+
+	if err := rangeProtoFields([]byte{0x0a, 0x05, 'a'}, func(protoField) error { return nil }); err == nil {
+		t.Error("expected an error for a truncated message")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// QRLogin is a sign in waiting on a phone (or ApproveAuthSession) to
+// scan the challenge, show ChallengeURL as a QR code and watch Updates
+// until you get QRLoginApproved, QRLoginExpired or QRLoginFailed after
+// which the channel is closed.
+type QRLogin struct {
+	ChallengeURL string
+	Updates      <-chan QRLoginUpdate
+
+	client    *Client
+	clientID  uint64
+	requestID []byte
+	interval  time.Duration
+}
+
+// QRLoginUpdate is sent whenever the state of a QRLogin changes,
+// ChallengeURL is set for QRLoginChallengeChanged, Session for
+// QRLoginApproved and Err for QRLoginFailed
+type QRLoginUpdate struct {
+	Status       QRLoginStatus
+	ChallengeURL string
+	AccountName  string
+	Session      *SessionData
+	Err          error
+}
+
+// BeginQRLogin starts a QR code sign in with the DefaultClient
+func BeginQRLogin(ctx context.Context) (*QRLogin, error) {
+	return DefaultClient.BeginQRLogin(ctx)
+}
+
+// BeginQRLogin starts a QR code sign in, polling carries on in the
+// background until the sign in is approved, expires or ctx is done
+func (c *Client) BeginQRLogin(ctx context.Context) (*QRLogin, error) {
+	c.log("Beginning QR login")
+	beginResponse := authBeginAuthSessionViaQRResponse{}
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/BeginAuthSessionViaQR/v1").
+		SetProtobuf(&authBeginAuthSessionViaQRRequest{
+			DeviceDetails: mobileDeviceDetails(),
+			WebsiteID:     "Mobile",
+		}).
+		HandleProtobuf(&beginResponse).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error %s", err)
+		return nil, err
+	}
+
+	if beginResponse.ClientID == 0 || beginResponse.ChallengeURL == "" {
+		c.logf("Protocol error: missing client id or challenge url")
+		return nil, errors.New("missing client id or challenge url")
+	}
+
+	updates := make(chan QRLoginUpdate, 1)
+	q := &QRLogin{
+		ChallengeURL: beginResponse.ChallengeURL,
+		Updates:      updates,
+		client:       c,
+		clientID:     beginResponse.ClientID,
+		requestID:    beginResponse.RequestID,
+		interval:     pollInterval(beginResponse.Interval),
+	}
+
+	go q.poll(ctx, updates)
+
+	return q, nil
+}
+
+func (q *QRLogin) poll(ctx context.Context, updates chan<- QRLoginUpdate) {
+	defer close(updates)
+
+	c := q.client
+	scanned := false
+	send := func(update QRLoginUpdate) bool {
+		select {
+		case updates <- update:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			send(QRLoginUpdate{Status: QRLoginFailed, Err: ctx.Err()})
+			return
+		case <-time.After(q.interval):
+		}
+
+		pollResponse := authPollAuthSessionStatusResponse{}
+		_, err := c.SteamWeb().
+			SetContext(ctx).
+			Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/PollAuthSessionStatus/v1").
+			SetProtobuf(&authPollAuthSessionStatusRequest{ClientID: q.clientID, RequestID: q.requestID}).
+			HandleProtobuf(&pollResponse).
+			Do()
+
+		if isEResult(err, EResultFileNotFound) || isEResult(err, EResultExpired) {
+			c.log(QRLoginExpired)
+			send(QRLoginUpdate{Status: QRLoginExpired})
+			return
+		}
+
+		if err != nil {
+			c.logf("Protocol error %s", err)
+			send(QRLoginUpdate{Status: QRLoginFailed, Err: err})
+			return
+		}
+
+		if pollResponse.NewChallengeURL != "" {
+			q.clientID = pollResponse.NewClientID
+			c.log(QRLoginChallengeChanged)
+			if !send(QRLoginUpdate{Status: QRLoginChallengeChanged, ChallengeURL: pollResponse.NewChallengeURL}) {
+				return
+			}
+		}
+
+		if pollResponse.HadRemoteInteraction && !scanned {
+			scanned = true
+			c.log(QRLoginScanned)
+			if !send(QRLoginUpdate{Status: QRLoginScanned, AccountName: pollResponse.AccountName}) {
+				return
+			}
+		}
+
+		if pollResponse.RefreshToken != "" {
+			steamID, err := jwtSteamID(pollResponse.RefreshToken)
+			if err != nil {
+				send(QRLoginUpdate{Status: QRLoginFailed, Err: err})
+				return
+			}
+
+			session := &SessionData{
+				SteamID:      steamID,
+				RefreshToken: pollResponse.RefreshToken,
+				SessionID:    generateSessionID(),
+			}
+			session.setAccessToken(pollResponse.AccessToken)
+
+			c.log(QRLoginApproved)
+			send(QRLoginUpdate{Status: QRLoginApproved, AccountName: pollResponse.AccountName, Session: session})
+			return
+		}
+	}
+}
+
+// QRLoginStatus is the state of a QRLogin
+type QRLoginStatus int
+
+// Various QRLoginStatus.
+// You can call .String() to get a human representation.
+const (
+	QRLoginScanned QRLoginStatus = iota
+	QRLoginChallengeChanged
+	QRLoginApproved
+	QRLoginExpired
+	QRLoginFailed
+)
+
+var qrLoginStatuses = []string{
+	QRLoginScanned:          "scanned",
+	QRLoginChallengeChanged: "challenge changed",
+	QRLoginApproved:         "approved",
+	QRLoginExpired:          "expired",
+	QRLoginFailed:           "failed",
+}
+
+func (q QRLoginStatus) String() string {
+	return qrLoginStatuses[q]
+}
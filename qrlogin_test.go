@@ -0,0 +1,165 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestQRLogin(t *testing.T) {
+	fastPolling(t, time.Millisecond, time.Millisecond)
+	refresh := testJWT(time.Now().Add(time.Hour))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaQR/v1", func(w http.ResponseWriter, r *http.Request) {
+		response := protoWriter{}
+		response.varint(1, 42)
+		response.string(2, "https://s.team/q/1/42")
+		response.bytes(3, []byte{1, 2, 3})
+		response = protowire.AppendTag(response, 4, protowire.Fixed32Type)
+		response = protowire.AppendFixed32(response, math.Float32bits(0.01))
+		w.Write(response)
+	})
+	polls := 0
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		response := protoWriter{}
+		switch polls++; polls {
+		case 1:
+			response.varint(1, 43)
+			response.string(2, "https://s.team/q/1/43")
+		case 2:
+			response.bool(5, true)
+			response.string(6, "test")
+		default:
+			response.string(3, refresh)
+			response.string(4, "access")
+			response.bool(5, true)
+			response.string(6, "test")
+		}
+		w.Write(response)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	qr, err := client.BeginQRLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qr.ChallengeURL != "https://s.team/q/1/42" {
+		t.Errorf("challenge url mismatched %s", qr.ChallengeURL)
+	}
+
+	statuses := []QRLoginStatus{}
+	var session *SessionData
+	for update := range qr.Updates {
+		statuses = append(statuses, update.Status)
+		if update.Status == QRLoginApproved {
+			session = update.Session
+		}
+		if update.Err != nil {
+			t.Fatal(update.Err)
+		}
+	}
+
+	expected := []QRLoginStatus{QRLoginChallengeChanged, QRLoginScanned, QRLoginApproved}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Fatalf("expected %v got %v", expected, statuses)
+		}
+	}
+
+	if session == nil || session.SteamID != SteamID(76561198263585543) || session.RefreshToken != refresh || session.AccessToken != "access" {
+		t.Errorf("session mismatched %#v", session)
+	}
+}
+
+func TestQRLoginZeroInterval(t *testing.T) {
+	fastPolling(t, 10*time.Millisecond, 100*time.Millisecond)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaQR/v1", func(w http.ResponseWriter, r *http.Request) {
+		response := protoWriter{}
+		response.varint(1, 42)
+		response.string(2, "https://s.team/q/1/42")
+		response = protowire.AppendTag(response, 4, protowire.Fixed32Type)
+		response = protowire.AppendFixed32(response, math.Float32bits(0))
+		w.Write(response)
+	})
+	polls := make(chan struct{}, 1000)
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case polls <- struct{}{}:
+		default:
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+	qr, err := client.BeginQRLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range qr.Updates {
+	}
+
+	if len(polls) > 5 {
+		t.Errorf("expected a zero interval to poll at the default, polled %d times", len(polls))
+	}
+}
+
+func TestQRLoginExpired(t *testing.T) {
+	fastPolling(t, time.Millisecond, time.Millisecond)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaQR/v1", func(w http.ResponseWriter, r *http.Request) {
+		response := protoWriter{}
+		response.varint(1, 42)
+		response.string(2, "https://s.team/q/1/42")
+		w.Write(response)
+	})
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-eresult", "9")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+
+	qr, err := client.BeginQRLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := <-qr.Updates
+	if update.Status != QRLoginExpired {
+		t.Errorf("expected expired, got %s %v", update.Status, update.Err)
+	}
+	if _, ok := <-qr.Updates; ok {
+		t.Error("expected updates to be closed")
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Steam Desktop Authenticator derives its keys with PBKDF2-SHA1 over
+// the passkey and a per file salt, files are AES-256-CBC
+const (
+	sdaIterations = 50000
+	sdaKeySize    = 32
+	sdaSaltSize   = 8
+)
+
+// SDAManifest is Steam Desktop Authenticator's manifest.json, the
+// settings are kept so they survive a round trip
+type SDAManifest struct {
+	Encrypted                     bool                `json:"encrypted"`
+	FirstRun                      bool                `json:"first_run"`
+	Entries                       []*SDAManifestEntry `json:"entries"`
+	PeriodicChecking              bool                `json:"periodic_checking"`
+	PeriodicCheckingInterval      int                 `json:"periodic_checking_interval"`
+	PeriodicCheckingCheckAll      bool                `json:"periodic_checking_checkall"`
+	AutoConfirmMarketTransactions bool                `json:"auto_confirm_market_transactions"`
+	AutoConfirmTrades             bool                `json:"auto_confirm_trades"`
+}
+
+// SDAManifestEntry is a maFile in the manifest, the IV and salt are
+// base64 and only set when the manifest is encrypted
+type SDAManifestEntry struct {
+	EncryptionIV   *string `json:"encryption_iv"`
+	EncryptionSalt *string `json:"encryption_salt"`
+	Filename       string  `json:"filename"`
+	SteamID        uint64  `json:"steamid"`
+}
+
+// ReadSDADir reads the manifest and every maFile in a Steam Desktop
+// Authenticator maFiles directory, the passkey is only needed if the
+// manifest is encrypted.
+//
+// Files that can't be read are returned as AccountErrors along with the
+// rest, a passkey that doesn't decrypt them fails with ErrBadPasskey
+func ReadSDADir(dir, passkey string) (*SDAManifest, []*SteamGuardAccount, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &SDAManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, nil, err
+	}
+
+	if manifest.Encrypted && passkey == "" {
+		return manifest, nil, ErrPasskeyRequired
+	}
+
+	var accounts []*SteamGuardAccount
+	var errs AccountErrors
+	for _, entry := range manifest.Entries {
+		account, err := readSDAFile(dir, manifest, entry, passkey)
+		if err != nil {
+			errs = append(errs, &AccountError{Account: entry.Filename, Err: err})
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	if len(errs) > 0 {
+		return manifest, accounts, errs
+	}
+	return manifest, accounts, nil
+}
+
+func readSDAFile(dir string, manifest *SDAManifest, entry *SDAManifestEntry, passkey string) (*SteamGuardAccount, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(entry.Filename)))
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Encrypted {
+		if entry.EncryptionSalt == nil || entry.EncryptionIV == nil {
+			return nil, fmt.Errorf("manifest entry for %s is missing its salt or iv", entry.Filename)
+		}
+		if b, err = sdaDecrypt(string(b), passkey, *entry.EncryptionSalt, *entry.EncryptionIV); err != nil {
+			return nil, err
+		}
+	}
+
+	account := &SteamGuardAccount{}
+	if err := json.Unmarshal(b, account); err != nil {
+		if manifest.Encrypted {
+			return nil, ErrBadPasskey
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+// WriteSDADir writes the accounts as maFiles named by SteamID along with
+// a manifest.json listing them, Steam Desktop Authenticator can open the
+// directory as is. Settings are copied from manifest which may be nil,
+// the files are encrypted with a fresh salt and IV each if passkey isn't
+// empty. Accounts need a session with a SteamID to be named
+func WriteSDADir(dir string, manifest *SDAManifest, accounts []*SteamGuardAccount, passkey string) error {
+	out := SDAManifest{PeriodicCheckingInterval: 5}
+	if manifest != nil {
+		out = *manifest
+	}
+	out.Encrypted = passkey != ""
+	out.Entries = make([]*SDAManifestEntry, 0, len(accounts))
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		steamID := accountSteamID(account)
+		if steamID == 0 {
+			return fmt.Errorf("%s: account has no steamid", account.AccountName)
+		}
+
+		b, err := json.Marshal(account)
+		if err != nil {
+			return err
+		}
+
+		entry := &SDAManifestEntry{Filename: steamID.String() + ".maFile", SteamID: uint64(steamID)}
+		if out.Encrypted {
+			data, salt, iv, err := sdaEncrypt(b, passkey)
+			if err != nil {
+				return err
+			}
+			b = []byte(data)
+			entry.EncryptionSalt, entry.EncryptionIV = &salt, &iv
+		}
+
+		if err := writeFileAtomic(filepath.Join(dir, entry.Filename), b); err != nil {
+			return err
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	b, err := json.Marshal(&out)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "manifest.json"), b)
+}
+
+// LoadSDADir adds every account in a Steam Desktop Authenticator
+// maFiles directory, see ReadSDADir
+func (m *AccountManager) LoadSDADir(dir, passkey string) error {
+	_, accounts, err := ReadSDADir(dir, passkey)
+	for _, account := range accounts {
+		if addErr := m.Add(account); addErr != nil && err == nil {
+			err = addErr
+		}
+	}
+	return err
+}
+
+func sdaKey(passkey string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passkey), salt, sdaIterations, sdaKeySize, sha1.New)
+}
+
+// sdaDecrypt takes the base64 file contents, salt and IV
+func sdaDecrypt(data, passkey, salt, iv string) ([]byte, error) {
+	return cbcDecryptMaFile(data, salt, iv, func(salt []byte) []byte {
+		return sdaKey(passkey, salt)
+	})
+}
+
+// sdaEncrypt returns the base64 file contents, salt and IV
+func sdaEncrypt(plaintext []byte, passkey string) (data, salt, iv string, err error) {
+	return cbcEncryptMaFile(plaintext, sdaSaltSize, func(salt []byte) []byte {
+		return sdaKey(passkey, salt)
+	})
+}
+
+// cbcDecryptMaFile undoes AES-256-CBC with PKCS7 padding, the way both
+// SDA and steamguard-cli encrypt maFiles, with the key derived from the
+// salt by key
+func cbcDecryptMaFile(data, salt, iv string, key func(salt []byte) []byte) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, err
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key(saltBytes))
+	if err != nil {
+		return nil, err
+	}
+	if len(ivBytes) != block.BlockSize() || len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("malformed encrypted maFile")
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, ciphertext)
+
+	// a wrong passkey almost always leaves bad padding behind
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, ErrBadPasskey
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrBadPasskey
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// cbcEncryptMaFile returns the base64 file contents, salt and IV
+func cbcEncryptMaFile(plaintext []byte, saltSize int, key func(salt []byte) []byte) (data, salt, iv string, err error) {
+	saltBytes := make([]byte, saltSize)
+	ivBytes := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(saltBytes); err != nil {
+		return
+	}
+	if _, err = rand.Read(ivBytes); err != nil {
+		return
+	}
+
+	block, err := aes.NewCipher(key(saltBytes))
+	if err != nil {
+		return
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, ivBytes).CryptBlocks(ciphertext, padded)
+
+	return base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(saltBytes),
+		base64.StdEncoding.EncodeToString(ivBytes), nil
+}
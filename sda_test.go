@@ -0,0 +1,69 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadSDADir(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"manifest.json": `{"encrypted":true,"first_run":false,"entries":[{"encryption_iv":"MDEyMzQ1Njc4OWFiY2RlZg==","encryption_salt":"c2FsdHNhbHQ=","filename":"76561198263585543.maFile","steamid":76561198263585543}],"periodic_checking":true,"periodic_checking_interval":10,"periodic_checking_checkall":false,"auto_confirm_market_transactions":false,"auto_confirm_trades":false}`,
+		// encrypted by openssl with the key from PBKDF2-SHA1("hunter2", "saltsalt", 50000)
+		"76561198263585543.maFile": "8zaSNPno8qeq3/lt+rZ6NkXoKOL3r1htHji7MnK91bF+8CDfx6sJ2U4obCYtNygxPTjDhGexSp1Ecsa7cDh1a94BKFfolPK6/JxmIWANJ0fPH+c0XwzZ/r48tVT6m3pvg4akduhWw20RXHxOaCdNhWGeAetr9WXmIEK6dukc1mjpzBAN5AC29rqWcvpOoUq2",
+	})
+
+	if _, _, err := ReadSDADir(dir, ""); !errors.Is(err, ErrPasskeyRequired) {
+		t.Errorf("expected ErrPasskeyRequired, got %v", err)
+	}
+	if _, _, err := ReadSDADir(dir, "wrong"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+
+	manifest, accounts, err := ReadSDADir(dir, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.PeriodicChecking || manifest.PeriodicCheckingInterval != 10 {
+		t.Errorf("manifest mismatched %#v", manifest)
+	}
+	if len(accounts) != 1 || accounts[0].AccountName != "sda" || accounts[0].Session.SteamID != 76561198263585543 || accounts[0].ServerTime.Unix() != 1469115000 {
+		t.Fatalf("accounts mismatched %#v", accounts)
+	}
+
+	for _, passkey := range []string{"", "new passkey"} {
+		out := filepath.Join(t.TempDir(), "maFiles")
+		if err := WriteSDADir(out, manifest, accounts, passkey); err != nil {
+			t.Fatal(err)
+		}
+
+		manager := NewAccountManager(nil)
+		if err := manager.LoadSDADir(out, passkey); err != nil {
+			t.Fatal(err)
+		}
+		account := manager.GetBySteamID(76561198263585543)
+		if account == nil || account.SharedSecret != accounts[0].SharedSecret || account.AccountName != "sda" {
+			t.Errorf("round trip with passkey %q mismatched %#v", passkey, account)
+		}
+
+		written, _, _ := ReadSDADir(out, passkey)
+		if written.Encrypted != (passkey != "") || written.PeriodicCheckingInterval != 10 || len(written.Entries) != 1 {
+			t.Errorf("written manifest mismatched %#v", written)
+		}
+	}
+}
@@ -4,28 +4,143 @@
 
 package steamauth
 
-import "net/http"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
+// SessionData is everything needed to talk to steam as a logged in user.
+// OAuthToken is only set by the legacy UserLogin, AuthSessionLogin sets
+// AccessToken and RefreshToken instead
 type SessionData struct {
 	SessionID        string
 	SteamLogin       string
 	SteamLoginSecure string
 	WebCookie        string
 	OAuthToken       string
+	AccessToken      string
+	RefreshToken     string
 	SteamID          SteamID
 }
 
+// AccessTokenExpiry decodes the expiry from the access token
+func (s *SessionData) AccessTokenExpiry() (time.Time, error) {
+	return jwtExpiry(s.AccessToken)
+}
+
+// RefreshTokenExpiry decodes the expiry from the refresh token
+func (s *SessionData) RefreshTokenExpiry() (time.Time, error) {
+	return jwtExpiry(s.RefreshToken)
+}
+
+// expiresWithin reports if the access token can be refreshed and
+// expires within the given window
+func (s *SessionData) expiresWithin(window time.Duration) bool {
+	if s.RefreshToken == "" {
+		return false
+	}
+	expiry, err := s.AccessTokenExpiry()
+	return err != nil || time.Until(expiry) < window
+}
+
+// accessToken returns whichever token steam services should be
+// authenticated with
+func (s *SessionData) accessToken() string {
+	return iif(s.AccessToken != "", s.AccessToken, s.OAuthToken)
+}
+
+// setAccessToken updates the token and the cookie derived from it
+func (s *SessionData) setAccessToken(accessToken string) {
+	s.AccessToken = accessToken
+	s.SteamLoginSecure = s.SteamID.String() + "%7C%7C" + accessToken
+}
+
+// jwtClaims are the parts of a steam issued JWT we care about
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+}
+
+// parseJWT pulls the claims out of a steam issued JWT, the signature
+// isn't checked as we're not the ones who have to trust it
+func parseJWT(token string) (claims jwtClaims, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// jwtExpiry pulls the exp claim out of a steam issued JWT
+func jwtExpiry(token string) (time.Time, error) {
+	claims, err := parseJWT(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// jwtSteamID pulls the steam id the token was issued to out of a JWT
+func jwtSteamID(token string) (SteamID, error) {
+	claims, err := parseJWT(token)
+	if err != nil {
+		return 0, err
+	}
+
+	steamID, err := strconv.ParseUint(claims.Sub, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	return SteamID(steamID), nil
+}
+
+// SetCookies for this session in the given jar against the community
+// endpoint of the DefaultClient
 func (s *SessionData) SetCookies(jar http.CookieJar) {
-	jar.(http.CookieJar).SetCookies(APIEndpoints.CommunityBase, []*http.Cookie{
-		&http.Cookie{Name: "mobileClientVersion", Value: "0 (2.1.3)", Path: "/", Domain: ".steamcommunity.com"},
-		&http.Cookie{Name: "mobileClient", Value: "android", Path: "/", Domain: ".steamcommunity.com"},
+	s.setCookies(jar, APIEndpoints.CommunityBase)
+}
+
+// setCookies sets the session cookies for communityBase, scoped to its
+// host so they're still sent when it points somewhere other than steam
+func (s *SessionData) setCookies(jar http.CookieJar, communityBase *url.URL) {
+	domain := communityBase.Hostname()
+	secure := communityBase.Scheme == "https"
+
+	cookies := []*http.Cookie{
+		&http.Cookie{Name: "mobileClientVersion", Value: "0 (2.1.3)", Path: "/", Domain: domain},
+		&http.Cookie{Name: "mobileClient", Value: "android", Path: "/", Domain: domain},
 
-		&http.Cookie{Name: "steamid", Value: s.SteamID.String(), Path: "/", Domain: ".steamcommunity.com"},
-		&http.Cookie{Name: "steamLogin", Value: s.SteamLogin, Path: "/", Domain: ".steamcommunity.com", HttpOnly: true},
+		&http.Cookie{Name: "steamid", Value: s.SteamID.String(), Path: "/", Domain: domain},
+		&http.Cookie{Name: "steamLogin", Value: s.SteamLogin, Path: "/", Domain: domain, HttpOnly: true},
 
-		&http.Cookie{Name: "steamLoginSecure", Value: s.SteamLoginSecure, Path: "/", Domain: ".steamcommunity.com", HttpOnly: true, Secure: true},
+		&http.Cookie{Name: "steamLoginSecure", Value: s.SteamLoginSecure, Path: "/", Domain: domain, HttpOnly: true, Secure: secure},
 
-		&http.Cookie{Name: "steam_language", Value: "english", Path: "/", Domain: ".steamcommunity.com"},
-		&http.Cookie{Name: "dob", Value: "", Path: "/", Domain: ".steamcommunity.com"},
-	})
+		&http.Cookie{Name: "steam_language", Value: "english", Path: "/", Domain: domain},
+		&http.Cookie{Name: "dob", Value: "", Path: "/", Domain: domain},
+	}
+	if s.SessionID != "" {
+		cookies = append(cookies, &http.Cookie{Name: "sessionid", Value: s.SessionID, Path: "/", Domain: domain})
+	}
+	jar.SetCookies(communityBase, cookies)
 }
@@ -0,0 +1,88 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testJWT returns an unsigned token that expires at the given time
+func testJWT(exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"typ":"JWT","alg":"EdDSA"}`)) + "." +
+		encode([]byte(fmt.Sprintf(`{"sub":"76561198263585543","exp":%d}`, exp.Unix()))) + "." +
+		encode([]byte("signature"))
+}
+
+func TestJWTExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	got, err := jwtExpiry(testJWT(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(exp) {
+		t.Errorf("expiry mismatched %s <> %s", got, exp)
+	}
+
+	for _, token := range []string{"", "a.b", "a.!!!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".c"} {
+		if _, err := jwtExpiry(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestRefreshSession(t *testing.T) {
+	newAccess := testJWT(time.Now().Add(time.Hour))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshToken := ""
+		rangeProtoFields(protoRequest(r), func(f protoField) error {
+			if f.num == 1 {
+				refreshToken = f.string()
+			}
+			return nil
+		})
+		if refreshToken != "refresh" {
+			w.Header().Set("X-eresult", "15")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		response := protoWriter{}
+		response.string(1, newAccess)
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Endpoints.SteamAPIBase, _ = url.Parse(server.URL)
+
+	account := &SteamGuardAccount{Session: &SessionData{
+		SteamID:      SteamID(76561198263585543),
+		AccessToken:  testJWT(time.Now().Add(time.Minute)),
+		RefreshToken: "refresh",
+	}}
+	account.SetClient(client)
+
+	if err := account.refreshSessionIfExpiring(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Session.AccessToken != newAccess {
+		t.Error("access token was not refreshed")
+	}
+	if account.Session.SteamLoginSecure != "76561198263585543%7C%7C"+newAccess {
+		t.Error("steamLoginSecure was not updated")
+	}
+	if account.Session.RefreshToken != "refresh" {
+		t.Error("refresh token should be kept when steam doesn't rotate it")
+	}
+}
@@ -6,26 +6,23 @@ package steamauth
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http/cookiejar"
 	"net/url"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var (
-	steamGuardCodeTranslations = []byte{50, 51, 52, 53, 54, 55, 56, 57, 66, 67, 68, 70, 71, 72, 74, 75, 77, 78, 80, 81, 82, 84, 86, 87, 88, 89}
-	confIDRegex                = regexp.MustCompile(`data-confid="(\d+)"`)
-	confKeyRegex               = regexp.MustCompile(`data-key="(\d+)"`)
-	confDescRegex              = regexp.MustCompile(`<div>((Confirm|Trade with|Sell -) .+)</div>`)
-)
+var steamGuardCodeTranslations = []byte{50, 51, 52, 53, 54, 55, 56, 57, 66, 67, 68, 70, 71, 72, 74, 75, 77, 78, 80, 81, 82, 84, 86, 87, 88, 89}
 
 // SteamGuardAccount is a structure to represent an authenticated
 // account, you need to save/export this data or you risk losing
@@ -40,10 +37,25 @@ type SteamGuardAccount struct {
 	TokenGID       string       `json:"token_gid"`
 	IdentitySecret string       `json:"identity_secret"`
 	Secret1        string       `json:"secret_1"`
-	Status         int          `json:"status"`
+	Status         EResult      `json:"status"`
 	DeviceID       string       `json:"device_id"`
 	FullyEnrolled  bool         `json:"fully_enrolled"`
 	Session        *SessionData `json:"session"`
+
+	client *Client
+}
+
+// SetClient binds this account to the given Client, accounts
+// that are not bound use the DefaultClient
+func (s *SteamGuardAccount) SetClient(c *Client) {
+	s.client = c
+}
+
+func (s *SteamGuardAccount) steamClient() *Client {
+	if s.client == nil {
+		return DefaultClient
+	}
+	return s.client
 }
 
 // Export the account data as a json string
@@ -73,45 +85,121 @@ func (s *SteamGuardAccount) Load(r io.Reader) error {
 }
 
 // DeactivateAuthenticator will disable steamguard for this authenticator
-func (s *SteamGuardAccount) DeactivateAuthenticator() bool {
-	postData := url.Values{
-		"steamid":           []string{s.Session.SteamID.String()},
-		"steamguard_scheme": []string{"2"},
-		"revocation_code":   []string{s.RevocationCode},
-		"access_token":      []string{s.Session.OAuthToken},
-	}
-
-	log("Requestiong to remove this authenticator")
-	removeResponse := RemoveAuthenticatorResponse{}
-	_, err := SteamWeb().
-		SetParams(postData).
-		Post(APIEndpoints.SteamAPIBase.String() + "/ITwoFactorService/RemoveAuthenticator/v0001").
-		HandleJSON(&removeResponse).
-		MobileLoginRequest()
+func (s *SteamGuardAccount) DeactivateAuthenticator() error {
+	return s.DeactivateAuthenticatorContext(context.Background())
+}
+
+// DeactivateAuthenticatorContext is DeactivateAuthenticator with a
+// context that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) DeactivateAuthenticatorContext(ctx context.Context) error {
+	if s.Session == nil {
+		return ErrNoSession
+	}
+
+	c := s.steamClient()
+	removeRequest := twoFactorRemoveAuthenticatorRequest{
+		RevocationCode:   s.RevocationCode,
+		RevocationReason: 1,
+		SteamGuardScheme: 2,
+	}
+
+	c.log("Requestiong to remove this authenticator")
+	removeResponse := twoFactorRemoveAuthenticatorResponse{}
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		SetAccessToken(s.Session.accessToken()).
+		Post(c.Endpoints.SteamAPIBase.String() + "/ITwoFactorService/RemoveAuthenticator/v1").
+		SetProtobuf(&removeRequest).
+		HandleProtobuf(&removeResponse).
+		Do()
+
+	if err == nil && !removeResponse.Success {
+		err = &SteamError{Op: "remove authenticator", Message: fmt.Sprintf("%d revocation attempts remaining", removeResponse.RevocationAttemptsRemaining)}
+	} else if err != nil {
+		c.logf("Protocol error: %s", err)
+	}
+
+	c.audit(s, AuditDeactivateAuthenticator, s.SerialNumber, "", err)
+	if err == nil {
+		c.notify(s, NotifyAuthenticatorRemoved, nil)
+	}
+	return err
+}
+
+// RefreshSession trades the refresh token in the session for a new
+// access token, if steam also rotates the refresh token it's replaced
+func (s *SteamGuardAccount) RefreshSession() error {
+	return s.RefreshSessionContext(context.Background())
+}
+
+// RefreshSessionContext is RefreshSession with a context that can
+// cancel any in-flight requests to steam
+func (s *SteamGuardAccount) RefreshSessionContext(ctx context.Context) error {
+	if s.Session == nil || s.Session.RefreshToken == "" {
+		return ErrNoSession
+	}
+
+	if expiry, err := s.Session.RefreshTokenExpiry(); err == nil && time.Now().After(expiry) {
+		return ErrSessionExpired
+	}
+
+	c := s.steamClient()
+	refreshRequest := authGenerateAccessTokenForAppRequest{
+		RefreshToken: s.Session.RefreshToken,
+		SteamID:      s.Session.SteamID,
+		RenewalType:  tokenRenewalAllow,
+	}
+
+	c.logf("Refreshing session for %s", s.AccountName)
+	refreshResponse := authGenerateAccessTokenForAppResponse{}
+	_, err := c.SteamWeb().
+		SetContext(ctx).
+		Post(c.Endpoints.SteamAPIBase.String() + "/IAuthenticationService/GenerateAccessTokenForApp/v1").
+		SetProtobuf(&refreshRequest).
+		HandleProtobuf(&refreshResponse).
+		Do()
 
 	if err != nil {
-		logf("unhandled internal error: %s", err)
-		return false
+		c.logf("Protocol error: %s", err)
+		return err
+	}
+
+	if refreshResponse.AccessToken == "" {
+		return &SteamError{Op: "refresh session", Message: "no access token"}
+	}
+
+	s.Session.setAccessToken(refreshResponse.AccessToken)
+	if refreshResponse.RefreshToken != "" {
+		s.Session.RefreshToken = refreshResponse.RefreshToken
 	}
 
-	return removeResponse.Response.Success
+	return nil
+}
+
+// refreshSessionIfExpiring is called ahead of confirmation requests so
+// long running bots don't fall over when the access token ages out
+func (s *SteamGuardAccount) refreshSessionIfExpiring(ctx context.Context) error {
+	c := s.steamClient()
+	if c.RefreshWithin <= 0 || !s.Session.expiresWithin(c.RefreshWithin) {
+		return nil
+	}
+	return s.RefreshSessionContext(ctx)
 }
 
 // GenerateSteamGuardCode for the this account at this time
-func (s *SteamGuardAccount) GenerateSteamGuardCode() string {
-	return s.GenerateSteamGuardCodeForTime(TimeAligner.GetSteamTime())
+func (s *SteamGuardAccount) GenerateSteamGuardCode() (string, error) {
+	return s.GenerateSteamGuardCodeForTime(s.steamClient().TimeAligner().GetSteamTime())
 }
 
 // GenerateSteamGuardCodeForTime for the given time
-func (s *SteamGuardAccount) GenerateSteamGuardCodeForTime(atTime time.Time) string {
+func (s *SteamGuardAccount) GenerateSteamGuardCodeForTime(atTime time.Time) (string, error) {
 	if s.SharedSecret == "" {
-		return ""
+		return "", ErrInvalidSharedSecret
 	}
 
 	sharedSecret, err := base64.StdEncoding.DecodeString(s.SharedSecret)
 	if err != nil {
-		logf("unhandled internal error: %s", err)
-		return ""
+		return "", fmt.Errorf("%w: %s", ErrInvalidSharedSecret, err)
 	}
 
 	buf := new(bytes.Buffer)
@@ -132,65 +220,256 @@ func (s *SteamGuardAccount) GenerateSteamGuardCodeForTime(atTime time.Time) stri
 		codePoint /= translationCount
 	}
 
-	return string(codeBytes)
+	return string(codeBytes), nil
 }
 
-func (s *SteamGuardAccount) FetchConfirmations() []*Confirmation {
-	urlStr := s.generateConfirmationURL("conf")
+// FetchConfirmations that are waiting on this account
+func (s *SteamGuardAccount) FetchConfirmations() ([]*Confirmation, error) {
+	return s.FetchConfirmationsContext(context.Background())
+}
+
+// FetchConfirmationsContext is FetchConfirmations with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) FetchConfirmationsContext(ctx context.Context) ([]*Confirmation, error) {
+	if s.Session == nil {
+		return nil, ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return nil, err
+	}
+
+	c := s.steamClient()
+	urlStr, err := s.generateConfirmationURL(ctx, "conf")
+	if err != nil {
+		return nil, err
+	}
+
 	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
-	s.Session.SetCookies(cookieJar)
+	c.setSessionCookies(s.Session, cookieJar)
 
-	resp, _ := SteamWeb().
+	resp, err := c.SteamWeb().
+		SetContext(ctx).
 		SetJar(cookieJar).
 		Get(urlStr).
 		Do()
 
+	if err != nil {
+		c.logf("Protocol error: %s", err)
+		return nil, err
+	}
+
 	defer resp.Body.Close()
-	response, _ := ioutil.ReadAll(resp.Body)
 
-	// Here the regex dragons are unleashed on the world
-	if !(confIDRegex.Match(response) && confKeyRegex.Match(response) && confDescRegex.Match(response)) {
-		return nil
+	// Steam bounces us off to the login page when the session is no good
+	if strings.Contains(resp.Request.URL.Path, "login") {
+		return nil, ErrSessionExpired
+	}
+
+	listResponse := confirmationListResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&listResponse); err != nil {
+		c.logf("Protocol error: %s", err)
+		return nil, err
 	}
 
-	confIDs := confIDRegex.FindAllSubmatch(response, -1)
-	confKeys := confKeyRegex.FindAllSubmatch(response, -1)
-	confDescs := confDescRegex.FindAllSubmatch(response, -1)
+	if listResponse.NeedAuth {
+		return nil, ErrSessionExpired
+	}
 
-	ret := make([]*Confirmation, len(confIDs))
+	if !listResponse.Success {
+		return nil, &SteamError{Op: "fetch confirmations", Message: iif(listResponse.Message != "", listResponse.Message, listResponse.Detail)}
+	}
 
-	for i := range confIDs {
-		ret[i] = &Confirmation{
-			ConfirmationDescription: string(confDescs[i][1]),
-			ConfirmationID:          string(confIDs[i][1]),
-			ConfirmationKey:         string(confKeys[i][1]),
+	ret := make([]*Confirmation, len(listResponse.Conf))
+	for i := range listResponse.Conf {
+		if ret[i], err = listResponse.Conf[i].confirmation(); err != nil {
+			return nil, err
 		}
 	}
 
-	return ret
+	c.notifyConfirmations(s, ret)
+	return ret, nil
+}
+
+// AcceptConfirmation allows the given confirmation
+func (s *SteamGuardAccount) AcceptConfirmation(conf *Confirmation) error {
+	return s.AcceptConfirmationContext(context.Background(), conf)
+}
+
+// AcceptConfirmationContext is AcceptConfirmation with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) AcceptConfirmationContext(ctx context.Context, conf *Confirmation) error {
+	err := s.sendConfirmationAjax(ctx, conf, "allow")
+	s.steamClient().audit(s, AuditAcceptConfirmation, conf.ConfirmationID, conf.Headline, err)
+	return err
+}
+
+// RejectConfirmation cancels the given confirmation
+func (s *SteamGuardAccount) RejectConfirmation(conf *Confirmation) error {
+	return s.RejectConfirmationContext(context.Background(), conf)
+}
+
+// RejectConfirmationContext is RejectConfirmation with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) RejectConfirmationContext(ctx context.Context, conf *Confirmation) error {
+	err := s.sendConfirmationAjax(ctx, conf, "cancel")
+	s.steamClient().audit(s, AuditRejectConfirmation, conf.ConfirmationID, conf.Headline, err)
+	return err
+}
+
+// ConfirmationBatchResult is the outcome of answering one batch
+// of confirmations, Err is nil if steam accepted the whole batch
+type ConfirmationBatchResult struct {
+	Confirmations []*Confirmation
+	Err           error
 }
 
-func (s *SteamGuardAccount) AcceptConfirmation(conf *Confirmation) bool {
-	return s.sendConfirmationAjax(conf, "allow")
+// AcceptConfirmations allows all of the given confirmations, they're
+// sent in batches of Client.ConfirmationBatchSize and the result of
+// each batch is returned along with the first error
+func (s *SteamGuardAccount) AcceptConfirmations(confs []*Confirmation) ([]ConfirmationBatchResult, error) {
+	return s.AcceptConfirmationsContext(context.Background(), confs)
 }
 
-func (s *SteamGuardAccount) RejectConfirmation(conf *Confirmation) bool {
-	return s.sendConfirmationAjax(conf, "cancel")
+// AcceptConfirmationsContext is AcceptConfirmations with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) AcceptConfirmationsContext(ctx context.Context, confs []*Confirmation) ([]ConfirmationBatchResult, error) {
+	return s.sendConfirmationBatches(ctx, confs, "allow")
 }
 
-func (s *SteamGuardAccount) sendConfirmationAjax(conf *Confirmation, op string) bool {
-	urlStr := APIEndpoints.CommunityBase.String() + "/mobileconf/ajaxop"
-	query := s.generateConfirmationQueryParams(op)
+// RejectConfirmations cancels all of the given confirmations, they're
+// sent in batches of Client.ConfirmationBatchSize and the result of
+// each batch is returned along with the first error
+func (s *SteamGuardAccount) RejectConfirmations(confs []*Confirmation) ([]ConfirmationBatchResult, error) {
+	return s.RejectConfirmationsContext(context.Background(), confs)
+}
+
+// RejectConfirmationsContext is RejectConfirmations with a context
+// that can cancel any in-flight requests to steam
+func (s *SteamGuardAccount) RejectConfirmationsContext(ctx context.Context, confs []*Confirmation) ([]ConfirmationBatchResult, error) {
+	return s.sendConfirmationBatches(ctx, confs, "cancel")
+}
+
+func (s *SteamGuardAccount) sendConfirmationBatches(ctx context.Context, confs []*Confirmation, op string) ([]ConfirmationBatchResult, error) {
+	c := s.steamClient()
+	batchSize := c.ConfirmationBatchSize
+	if batchSize <= 0 {
+		batchSize = len(confs)
+	}
+
+	action := AuditRejectConfirmation
+	if op == "allow" {
+		action = AuditAcceptConfirmation
+	}
+
+	var results []ConfirmationBatchResult
+	var firstErr, fatalErr error
+	for len(confs) > 0 {
+		n := batchSize
+		if n > len(confs) {
+			n = len(confs)
+		}
+		result := ConfirmationBatchResult{Confirmations: confs[:n]}
+		confs = confs[n:]
+
+		// No point carrying on once the session, the rate limit or
+		// the context has given out, the rest would fail the same way
+		if result.Err = fatalErr; result.Err == nil {
+			result.Err = s.sendMultiConfirmationAjax(ctx, result.Confirmations, op)
+			if errors.Is(result.Err, ErrSessionExpired) || errors.Is(result.Err, ErrNoSession) || errors.Is(result.Err, ErrRateLimited) || ctx.Err() != nil {
+				fatalErr = result.Err
+			}
+		}
+
+		for _, conf := range result.Confirmations {
+			c.audit(s, action, conf.ConfirmationID, conf.Headline, result.Err)
+		}
+
+		if firstErr == nil {
+			firstErr = result.Err
+		}
+		results = append(results, result)
+	}
+
+	return results, firstErr
+}
+
+func (s *SteamGuardAccount) sendMultiConfirmationAjax(ctx context.Context, confs []*Confirmation, op string) error {
+	if s.Session == nil {
+		return ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return err
+	}
+
+	c := s.steamClient()
+	urlStr := c.Endpoints.CommunityBase.String() + "/mobileconf/multiajaxop"
+	params, err := s.generateConfirmationQueryParams(ctx, op)
+	if err != nil {
+		return err
+	}
+	params.Set("op", op)
+	for _, conf := range confs {
+		params.Add("cid[]", conf.ConfirmationID)
+		params.Add("ck[]", conf.ConfirmationKey)
+	}
+
+	confResponse := SendConfirmationResponse{}
+	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
+	c.setSessionCookies(s.Session, cookieJar)
+
+	c.logf("requesting to %s %d confirmations", op, len(confs))
+	_, err = c.SteamWeb().
+		SetContext(ctx).
+		SetJar(cookieJar).
+		SetParams(params).
+		Post(urlStr).
+		HandleJSON(&confResponse).
+		Do()
+
+	if err != nil {
+		c.logf("Protocol error: %s", err)
+		return err
+	}
+
+	if confResponse.NeedAuth {
+		return ErrSessionExpired
+	}
+
+	if !confResponse.Success {
+		return &SteamError{Op: fmt.Sprintf("%s %d confirmations", op, len(confs)), Message: confResponse.Message}
+	}
+
+	return nil
+}
+
+func (s *SteamGuardAccount) sendConfirmationAjax(ctx context.Context, conf *Confirmation, op string) error {
+	if s.Session == nil {
+		return ErrNoSession
+	}
+
+	if err := s.refreshSessionIfExpiring(ctx); err != nil {
+		return err
+	}
+
+	c := s.steamClient()
+	urlStr := c.Endpoints.CommunityBase.String() + "/mobileconf/ajaxop"
+	query, err := s.generateConfirmationQueryParams(ctx, op)
+	if err != nil {
+		return err
+	}
 	query.Set("op", op)
 	query.Set("cid", conf.ConfirmationID)
 	query.Set("ck", conf.ConfirmationKey)
 
 	confResponse := SendConfirmationResponse{}
 	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
-	s.Session.SetCookies(cookieJar)
+	c.setSessionCookies(s.Session, cookieJar)
 
-	logf("requesting to %s confirmation ajax for %s", op, conf.ConfirmationID)
-	_, err := SteamWeb().
+	c.logf("requesting to %s confirmation ajax for %s", op, conf.ConfirmationID)
+	_, err = c.SteamWeb().
+		SetContext(ctx).
 		SetJar(cookieJar).
 		SetParams(query).
 		Get(urlStr).
@@ -198,33 +477,60 @@ func (s *SteamGuardAccount) sendConfirmationAjax(conf *Confirmation, op string)
 		Do()
 
 	if err != nil {
-		logf("unhandled internal error: %s", err)
-		return false
+		c.logf("Protocol error: %s", err)
+		return err
+	}
+
+	if confResponse.NeedAuth {
+		return ErrSessionExpired
+	}
+
+	if !confResponse.Success {
+		return &SteamError{Op: op + " confirmation " + conf.ConfirmationID, Message: confResponse.Message}
 	}
 
-	return confResponse.Success
+	return nil
 }
 
-func (s *SteamGuardAccount) generateConfirmationURL(tag string) string {
-	endpoint := APIEndpoints.CommunityBase.String() + "/mobileconf/conf?"
-	query := s.generateConfirmationQueryParams(tag)
-	return endpoint + query.Encode()
+func (s *SteamGuardAccount) generateConfirmationURL(ctx context.Context, tag string) (string, error) {
+	endpoint := s.steamClient().Endpoints.CommunityBase.String() + "/mobileconf/getlist?"
+	query, err := s.generateConfirmationQueryParams(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+	return endpoint + query.Encode(), nil
 }
 
-func (s *SteamGuardAccount) generateConfirmationQueryParams(tag string) url.Values {
-	atTime := TimeAligner.GetSteamTime()
+func (s *SteamGuardAccount) generateConfirmationQueryParams(ctx context.Context, tag string) (url.Values, error) {
+	atTime, err := s.steamClient().TimeAligner().GetSteamTimeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.generateConfirmationHashForTime(atTime, tag)
+	if err != nil {
+		return nil, err
+	}
 	return url.Values{
 		"p":   []string{s.DeviceID},
 		"a":   []string{s.Session.SteamID.String()},
-		"k":   []string{s.generateConfirmationHashForTime(atTime, tag)},
+		"k":   []string{hash},
 		"t":   []string{strconv.FormatInt(atTime.Unix(), 10)},
 		"m":   []string{"android"},
 		"tag": []string{tag},
-	}
+	}, nil
 }
 
-func (s *SteamGuardAccount) generateConfirmationHashForTime(atTime time.Time, tag string) string {
-	decode, _ := base64.StdEncoding.DecodeString(s.IdentitySecret)
+// generateConfirmationHashForTime returns the base64 hash, it's
+// escaped along with the rest of the query so don't do it here
+func (s *SteamGuardAccount) generateConfirmationHashForTime(atTime time.Time, tag string) (string, error) {
+	if s.IdentitySecret == "" {
+		return "", ErrInvalidIdentitySecret
+	}
+
+	decode, err := base64.StdEncoding.DecodeString(s.IdentitySecret)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIdentitySecret, err)
+	}
 
 	tagLen := len(tag)
 	if tagLen > 32 {
@@ -233,18 +539,19 @@ func (s *SteamGuardAccount) generateConfirmationHashForTime(atTime time.Time, ta
 
 	buf := new(bytes.Buffer)
 	binary.Write(buf, binary.BigEndian, atTime.Unix())
-	binary.Write(buf, binary.BigEndian, tag[0:tagLen])
+	buf.WriteString(tag[0:tagLen])
 
 	mac := hmac.New(sha1.New, decode)
 	mac.Write(buf.Bytes())
 	hashedData := mac.Sum(nil)
 
-	encoded := base64.StdEncoding.EncodeToString(hashedData)
-
-	return url.QueryEscape(encoded)
+	return base64.StdEncoding.EncodeToString(hashedData), nil
 }
 
 // RemoveAuthenticatorResponse contains the response to the request to remove the authenticator
+//
+// Deprecated: DeactivateAuthenticator speaks protobuf now, this is kept
+// so code that refers to it still builds
 type RemoveAuthenticatorResponse struct {
 	Response struct {
 		Success bool `json:"success"`
@@ -253,5 +560,7 @@ type RemoveAuthenticatorResponse struct {
 
 // SendConfirmationResponse contains the response to confirmation requests
 type SendConfirmationResponse struct {
-	Success bool `json:"success"`
+	Success  bool   `json:"success"`
+	NeedAuth bool   `json:"needauth"`
+	Message  string `json:"message"`
 }
@@ -0,0 +1,45 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGenerateSteamGuardCodeForTime(t *testing.T) {
+	account := SteamGuardAccount{SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="}
+
+	code, err := account.GenerateSteamGuardCodeForTime(time.Unix(1469115000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 5 {
+		t.Errorf("expected a 5 character code, got %q", code)
+	}
+
+	again, _ := account.GenerateSteamGuardCodeForTime(time.Unix(1469115029, 0))
+	if again != code {
+		t.Errorf("codes in the same 30 second window differ, %q <> %q", code, again)
+	}
+
+	for _, secret := range []string{"", "not base64!"} {
+		account := SteamGuardAccount{SharedSecret: secret}
+		if _, err := account.GenerateSteamGuardCodeForTime(time.Now()); !errors.Is(err, ErrInvalidSharedSecret) {
+			t.Errorf("expected ErrInvalidSharedSecret for %q, got %v", secret, err)
+		}
+	}
+}
+
+func TestSteamErrorIs(t *testing.T) {
+	var err error = &SteamError{Op: "test", EResult: 2}
+	if !errors.Is(err, ErrSteamRejected) {
+		t.Error("SteamError should match ErrSteamRejected")
+	}
+	if errors.Is(err, ErrSessionExpired) {
+		t.Error("SteamError should not match ErrSessionExpired")
+	}
+}
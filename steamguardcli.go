@@ -0,0 +1,262 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// steamguard-cli derives its keys with argon2id (version 0x13) over the
+// passkey and a per file salt, files are AES-256-CBC the same as SDA.
+// These match src/encryption/argon2id_aes.rs
+const (
+	steamGuardCLIManifestVersion = 1
+
+	steamGuardCLIArgonTime    = 3
+	steamGuardCLIArgonMemory  = 12 * 4096
+	steamGuardCLIArgonThreads = 4
+	steamGuardCLIKeySize      = 32
+	steamGuardCLISaltSize     = 16
+)
+
+// Encryption schemes used by steamguard-cli manifest entries
+const (
+	SteamGuardCLIArgon2idAes256      = "Argon2idAes256"
+	SteamGuardCLILegacySdaCompatible = "LegacySdaCompatible"
+)
+
+// SteamGuardCLIManifest is the manifest.json of a steamguard-cli maFiles
+// directory, version 0 (which only knew SDA compatible encryption) and
+// version 1 are understood
+type SteamGuardCLIManifest struct {
+	Version                       int                           `json:"version"`
+	Entries                       []*SteamGuardCLIManifestEntry `json:"entries"`
+	KeyringID                     *string                       `json:"keyring_id"`
+	AutoConfirmMarketTransactions bool                          `json:"auto_confirm_market_transactions"`
+	AutoConfirmTrades             bool                          `json:"auto_confirm_trades"`
+}
+
+// SteamGuardCLIManifestEntry is a maFile in the manifest, Encryption
+// is nil if the file isn't encrypted
+type SteamGuardCLIManifestEntry struct {
+	Filename    string                   `json:"filename"`
+	SteamID     uint64                   `json:"steam_id"`
+	AccountName string                   `json:"account_name"`
+	Encryption  *SteamGuardCLIEncryption `json:"encryption"`
+}
+
+// SteamGuardCLIEncryption is how a maFile was encrypted, the salt and
+// IV are base64
+type SteamGuardCLIEncryption struct {
+	IV     string `json:"iv"`
+	Salt   string `json:"salt"`
+	Scheme string `json:"scheme"`
+}
+
+// steamGuardCLIAccount is what steamguard-cli writes, a SteamGuardAccount
+// with the steam id and tokens alongside rather than in the session
+type steamGuardCLIAccount struct {
+	*SteamGuardAccount
+	SteamID uint64               `json:"steam_id,omitempty"`
+	Tokens  *steamGuardCLITokens `json:"tokens,omitempty"`
+}
+
+type steamGuardCLITokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ReadSteamGuardCLIDir reads the manifest and every maFile in a
+// steamguard-cli maFiles directory, the passkey is only needed if any
+// of them are encrypted.
+//
+// Files that can't be read are returned as AccountErrors along with the
+// rest, a passkey that doesn't decrypt them fails with ErrBadPasskey
+func ReadSteamGuardCLIDir(dir, passkey string) (*SteamGuardCLIManifest, []*SteamGuardAccount, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &SteamGuardCLIManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, nil, err
+	}
+
+	if manifest.Version < 0 || manifest.Version > steamGuardCLIManifestVersion {
+		return manifest, nil, fmt.Errorf("%w: steamguard-cli manifest version %d", ErrUnsupportedVersion, manifest.Version)
+	}
+
+	var accounts []*SteamGuardAccount
+	var errs AccountErrors
+	for _, entry := range manifest.Entries {
+		account, err := readSteamGuardCLIFile(dir, manifest, entry, passkey)
+		if err != nil {
+			errs = append(errs, &AccountError{Account: iif(entry.AccountName != "", entry.AccountName, entry.Filename), Err: err})
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	if len(errs) > 0 {
+		return manifest, accounts, errs
+	}
+	return manifest, accounts, nil
+}
+
+func readSteamGuardCLIFile(dir string, manifest *SteamGuardCLIManifest, entry *SteamGuardCLIManifestEntry, passkey string) (*SteamGuardAccount, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(entry.Filename)))
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Encryption != nil {
+		if passkey == "" {
+			return nil, ErrPasskeyRequired
+		}
+
+		scheme := entry.Encryption.Scheme
+		if scheme == "" && manifest.Version == 0 {
+			scheme = SteamGuardCLILegacySdaCompatible
+		}
+
+		switch scheme {
+		case SteamGuardCLIArgon2idAes256:
+			b, err = steamGuardCLIDecrypt(string(b), passkey, entry.Encryption.Salt, entry.Encryption.IV)
+		case SteamGuardCLILegacySdaCompatible:
+			b, err = sdaDecrypt(string(b), passkey, entry.Encryption.Salt, entry.Encryption.IV)
+		default:
+			return nil, fmt.Errorf("%w: steamguard-cli encryption scheme %q", ErrUnsupportedVersion, scheme)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wrapped := &steamGuardCLIAccount{SteamGuardAccount: &SteamGuardAccount{}}
+	if err := json.Unmarshal(b, wrapped); err != nil {
+		if entry.Encryption != nil {
+			return nil, ErrBadPasskey
+		}
+		return nil, err
+	}
+
+	account := wrapped.SteamGuardAccount
+	if account.Session == nil && (wrapped.SteamID != 0 || wrapped.Tokens != nil) {
+		account.Session = &SessionData{}
+	}
+	if account.Session != nil {
+		if account.Session.SteamID == 0 {
+			account.Session.SteamID = SteamID(wrapped.SteamID)
+		}
+		if account.Session.SteamID == 0 {
+			account.Session.SteamID = SteamID(entry.SteamID)
+		}
+		if wrapped.Tokens != nil && account.Session.RefreshToken == "" {
+			account.Session.RefreshToken = wrapped.Tokens.RefreshToken
+			account.Session.setAccessToken(wrapped.Tokens.AccessToken)
+		}
+	}
+	if account.AccountName == "" {
+		account.AccountName = entry.AccountName
+	}
+
+	return account, nil
+}
+
+// WriteSteamGuardCLIDir writes the accounts as maFiles named by account
+// name along with a version 1 manifest.json listing them, steamguard-cli
+// can open the directory as is. Settings are copied from manifest which
+// may be nil, the files are encrypted with a fresh salt and IV each if
+// passkey isn't empty
+func WriteSteamGuardCLIDir(dir string, manifest *SteamGuardCLIManifest, accounts []*SteamGuardAccount, passkey string) error {
+	out := SteamGuardCLIManifest{}
+	if manifest != nil {
+		out = *manifest
+	}
+	out.Version = steamGuardCLIManifestVersion
+	out.Entries = make([]*SteamGuardCLIManifestEntry, 0, len(accounts))
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if account.AccountName == "" {
+			return fmt.Errorf("account has no account name")
+		}
+
+		wrapped := &steamGuardCLIAccount{SteamGuardAccount: account, SteamID: uint64(accountSteamID(account))}
+		if account.Session != nil && account.Session.RefreshToken != "" {
+			wrapped.Tokens = &steamGuardCLITokens{AccessToken: account.Session.AccessToken, RefreshToken: account.Session.RefreshToken}
+		}
+		b, err := json.Marshal(wrapped)
+		if err != nil {
+			return err
+		}
+
+		entry := &SteamGuardCLIManifestEntry{
+			Filename:    strings.ToLower(account.AccountName) + ".maFile",
+			SteamID:     wrapped.SteamID,
+			AccountName: account.AccountName,
+		}
+		if passkey != "" {
+			data, salt, iv, err := steamGuardCLIEncrypt(b, passkey)
+			if err != nil {
+				return err
+			}
+			b = []byte(data)
+			entry.Encryption = &SteamGuardCLIEncryption{IV: iv, Salt: salt, Scheme: SteamGuardCLIArgon2idAes256}
+		}
+
+		if err := writeFileAtomic(filepath.Join(dir, entry.Filename), b); err != nil {
+			return err
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	b, err := json.Marshal(&out)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "manifest.json"), b)
+}
+
+// LoadSteamGuardCLIDir adds every account in a steamguard-cli maFiles
+// directory, see ReadSteamGuardCLIDir
+func (m *AccountManager) LoadSteamGuardCLIDir(dir, passkey string) error {
+	_, accounts, err := ReadSteamGuardCLIDir(dir, passkey)
+	for _, account := range accounts {
+		if addErr := m.Add(account); addErr != nil && err == nil {
+			err = addErr
+		}
+	}
+	return err
+}
+
+func steamGuardCLIKey(passkey string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passkey), salt, steamGuardCLIArgonTime, steamGuardCLIArgonMemory, steamGuardCLIArgonThreads, steamGuardCLIKeySize)
+}
+
+// steamGuardCLIDecrypt takes the base64 file contents, salt and IV
+func steamGuardCLIDecrypt(data, passkey, salt, iv string) ([]byte, error) {
+	return cbcDecryptMaFile(data, salt, iv, func(salt []byte) []byte {
+		return steamGuardCLIKey(passkey, salt)
+	})
+}
+
+// steamGuardCLIEncrypt returns the base64 file contents, salt and IV
+func steamGuardCLIEncrypt(plaintext []byte, passkey string) (data, salt, iv string, err error) {
+	return cbcEncryptMaFile(plaintext, steamGuardCLISaltSize, func(salt []byte) []byte {
+		return steamGuardCLIKey(passkey, salt)
+	})
+}
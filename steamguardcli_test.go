@@ -0,0 +1,95 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSteamGuardCLIDir(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"manifest.json": `{"version":1,"entries":[{"filename":"plain.maFile","steam_id":76561198263585543,"account_name":"plain","encryption":null}],"keyring_id":null,"auto_confirm_market_transactions":false,"auto_confirm_trades":true}`,
+		"plain.maFile":  `{"shared_secret":"cnOgv/KdpLoP6Nbh0GMkXkPXALQ=","account_name":"plain","steam_id":76561198263585543,"tokens":{"access_token":"access","refresh_token":"refresh"}}`,
+	})
+
+	manifest, accounts, err := ReadSteamGuardCLIDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.AutoConfirmTrades || len(accounts) != 1 {
+		t.Fatalf("manifest mismatched %#v", manifest)
+	}
+	account := accounts[0]
+	if account.Session == nil || account.Session.SteamID != 76561198263585543 || account.Session.RefreshToken != "refresh" || account.Session.AccessToken != "access" {
+		t.Errorf("session mismatched %#v", account.Session)
+	}
+
+	out := filepath.Join(t.TempDir(), "maFiles")
+	if err := WriteSteamGuardCLIDir(out, manifest, accounts, "passkey"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ReadSteamGuardCLIDir(out, ""); !errors.Is(err, ErrPasskeyRequired) {
+		t.Errorf("expected ErrPasskeyRequired, got %v", err)
+	}
+	if _, _, err := ReadSteamGuardCLIDir(out, "wrong"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+
+	manager := NewAccountManager(nil)
+	if err := manager.LoadSteamGuardCLIDir(out, "passkey"); err != nil {
+		t.Fatal(err)
+	}
+	if account := manager.Get("plain"); account == nil || account.SharedSecret != "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=" || account.Session.RefreshToken != "refresh" {
+		t.Errorf("round trip mismatched %#v", account)
+	}
+
+	written, _, _ := ReadSteamGuardCLIDir(out, "passkey")
+	if written.Version != 1 || !written.AutoConfirmTrades || written.Entries[0].Encryption.Scheme != SteamGuardCLIArgon2idAes256 {
+		t.Errorf("written manifest mismatched %#v", written)
+	}
+}
+
+func TestReadSteamGuardCLIDirArgon2id(t *testing.T) {
+	// generated outside this package: the key from an RFC 9106 argon2id
+	// (m=48MiB, t=3, p=4) over passkey "hunter2" and salt "saltsaltsaltsalt"
+	// then openssl enc -aes-256-cbc with iv "0123456789abcdef"
+	dir := writeTestFiles(t, map[string]string{
+		"manifest.json": `{"version":1,"entries":[{"filename":"argon.maFile","steam_id":76561198263585543,"account_name":"argon","encryption":{"iv":"MDEyMzQ1Njc4OWFiY2RlZg==","salt":"c2FsdHNhbHRzYWx0c2FsdA==","scheme":"Argon2idAes256"}}],"keyring_id":null,"auto_confirm_market_transactions":false,"auto_confirm_trades":false}`,
+		"argon.maFile":  "ZLCgeNUnDtP5XFEPz2SsjWDFIPET9uO/R42gx8OnuvAN6OxORWGe1ByZVWp2+7TCWGft5jUEmLY2qufFgBWey7a0uvHneDLQeIPyBrxFtzgbJvzX3wcOGFeko/g8wFjDWKv59NK5oEl3LIq+7nK/w5ggwf4SSUFsYJTzaJUCcfIFbd/sZwm4U/VshXeSLL20syBRfeKcmThV3Ge3VanLzrc8Yy7Gh0+jeS1zJYNpBZCW8mcE82Jp1L2aCY1jA1Fs3Xr95JAYho7PEgBbRJiyvsh+V7MlhPiNSEvT0faf+83b3h+PHKo+ixxSvSYC4WgJI0rWfn22GKnR8LHXY0SF1r6i7u3C7opOdtXBMi2M+V8FSzJa1Z+AFApmyHGGRMYkff/Bzmyovc0uSiyHp6zusiKGTHEd+nU/bSnElbIyboo64rS9+nj4AN19keqDCNr2Oep8TEaMO1dwh57RUSXsmG7mEvsHxTudo8T33F2NhT7jho8rI02wKywkHvuk6Ca+wsQyYXeLxfOY/vUbjdXfCC1PxaaVh0S7c+PDrfmhHGU=",
+	})
+
+	_, accounts, err := ReadSteamGuardCLIDir(dir, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := accounts[0]
+	if account.AccountName != "argon" || account.RevocationCode != "R12345" || account.Session == nil || account.Session.SteamID != 76561198263585543 || account.Session.RefreshToken != "refresh" {
+		t.Errorf("account mismatched %#v", account)
+	}
+
+	if _, _, err := ReadSteamGuardCLIDir(dir, "hunter3"); !errors.Is(err, ErrBadPasskey) {
+		t.Errorf("expected ErrBadPasskey, got %v", err)
+	}
+}
+
+func TestReadSteamGuardCLIDirVersions(t *testing.T) {
+	// version 0 entries are encrypted the same way as Steam Desktop Authenticator
+	dir := writeTestFiles(t, map[string]string{
+		"manifest.json": `{"version":0,"entries":[{"filename":"sda.maFile","steamid":76561198263585543,"account_name":"sda","encryption":{"iv":"MDEyMzQ1Njc4OWFiY2RlZg==","salt":"c2FsdHNhbHQ="}}]}`,
+		"sda.maFile":    "8zaSNPno8qeq3/lt+rZ6NkXoKOL3r1htHji7MnK91bF+8CDfx6sJ2U4obCYtNygxPTjDhGexSp1Ecsa7cDh1a94BKFfolPK6/JxmIWANJ0fPH+c0XwzZ/r48tVT6m3pvg4akduhWw20RXHxOaCdNhWGeAetr9WXmIEK6dukc1mjpzBAN5AC29rqWcvpOoUq2",
+	})
+	_, accounts, err := ReadSteamGuardCLIDir(dir, "hunter2")
+	if err != nil || len(accounts) != 1 || accounts[0].AccountName != "sda" {
+		t.Errorf("expected the version 0 manifest to load, got %v %v", accounts, err)
+	}
+
+	dir = writeTestFiles(t, map[string]string{"manifest.json": `{"version":99,"entries":[]}`})
+	if _, _, err := ReadSteamGuardCLIDir(dir, ""); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
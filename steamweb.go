@@ -5,22 +5,28 @@
 package steamauth
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 )
-import "net/url"
 
 type responseHandlerFunc func(*http.Response) error
 
 type steamWeb struct {
 	*http.Client
-	headers http.Header
-	params  url.Values
-	urlStr  string
-	method  string
+	client      *Client
+	ctx         context.Context
+	headers     http.Header
+	params      url.Values
+	accessToken string
+	urlStr      string
+	method      string
 
 	oV interface{}
 	oF responseHandlerFunc
@@ -30,14 +36,22 @@ type steamWeb struct {
 // you to perform requests against the steam API with a simple sequence
 // of method calls.
 func SteamWeb() *steamWeb {
+	return DefaultClient.SteamWeb()
+}
+
+func newSteamWeb(c *Client) *steamWeb {
+	// Copy the http client so SetJar doesn't leak between requests
+	httpClient := *c.HTTPClient
 	return &steamWeb{
-		Client: &http.Client{},
+		Client: &httpClient,
+		client: c,
 		headers: http.Header{
 			"User-Agent": []string{"Mozilla/5.0 (Linux; U; Android 4.1.1; en-us; Google Nexus 4 - 4.1.1 - API 16 - 768x1280 Build/JRO03S) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"},
 			"Accept":     []string{"text/javascript, text/html, application/xml, text/xml, */*"},
 		},
 		params: url.Values{},
 		method: "GET",
+		ctx:    context.Background(),
 	}
 }
 
@@ -45,14 +59,19 @@ func (s *steamWeb) newRequest() (*http.Request, error) {
 	var body io.Reader
 	urlStr := s.urlStr
 
+	// The access token always goes in the query, even for a POST
+	if s.accessToken != "" {
+		urlStr += iif(strings.Contains(urlStr, "?"), "&", "?") + "access_token=" + url.QueryEscape(s.accessToken)
+	}
+
 	if len(s.params) > 0 {
 		switch s.method {
 		case "GET":
 			query := s.params.Encode()
 			if strings.Contains(urlStr, "?") {
-				urlStr = "&" + query
+				urlStr += "&" + query
 			} else {
-				urlStr = "?" + query
+				urlStr += "?" + query
 			}
 		case "POST":
 			body = strings.NewReader(s.params.Encode())
@@ -60,7 +79,7 @@ func (s *steamWeb) newRequest() (*http.Request, error) {
 		}
 	}
 
-	req, err := http.NewRequest(s.method, urlStr, body)
+	req, err := http.NewRequestWithContext(s.ctx, s.method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +89,13 @@ func (s *steamWeb) newRequest() (*http.Request, error) {
 	return req, nil
 }
 
+// SetContext sets the context this request is made under, cancelling
+// it or hitting its deadline aborts the request
+func (s *steamWeb) SetContext(ctx context.Context) *steamWeb {
+	s.ctx = ctx
+	return s
+}
+
 // SetJar set this requests cookiejar
 func (s *steamWeb) SetJar(cookieJar http.CookieJar) *steamWeb {
 	s.Jar = cookieJar
@@ -116,6 +142,21 @@ func (s *steamWeb) SetParams(data url.Values) *steamWeb {
 	return s
 }
 
+// SetAccessToken sets the access token steam services
+// use to authenticate the request
+func (s *steamWeb) SetAccessToken(token string) *steamWeb {
+	s.accessToken = token
+	return s
+}
+
+// SetProtobuf encodes the message as the `input_protobuf_encoded`
+// parameter steam services expect, call it after `SetParams`
+// or it'll be overwritten
+func (s *steamWeb) SetProtobuf(m protoMarshaler) *steamWeb {
+	s.params.Set("input_protobuf_encoded", base64.StdEncoding.EncodeToString(m.marshalProto()))
+	return s
+}
+
 // Post allows you to prepare a post request for a given url
 func (s *steamWeb) Post(urlStr string) *steamWeb {
 	s.urlStr = urlStr
@@ -138,14 +179,28 @@ func (s *steamWeb) HandleJSON(v interface{}) *steamWeb {
 	return s
 }
 
+// HandleProtobuf will configure the request to parse a protobuf
+// response into the given message
+func (s *steamWeb) HandleProtobuf(m protoUnmarshaler) *steamWeb {
+	s.oV = m
+	s.oF = s.handleProtobuf
+	return s
+}
+
 // Do the request, execute it then do any post processing
 func (s *steamWeb) Do() (*http.Response, error) {
 	req, err := s.newRequest()
 	if err == nil {
-		logRequest(req)
-		logCookies(s, req)
+		s.client.logRequest(req)
+		s.client.logCookies(s, req)
 		resp, err := s.Client.Do(req)
-		logResponse(resp)
+		s.client.logResponse(resp)
+
+		if err == nil {
+			if err = checkStatus(resp); err != nil {
+				resp.Body.Close()
+			}
+		}
 
 		// Ouput format the content via the handle function...
 		if err == nil && s.oF != nil {
@@ -161,10 +216,27 @@ func (s *steamWeb) Do() (*http.Response, error) {
 // before executing the request
 func (s *steamWeb) MobileLoginRequest() (*http.Response, error) {
 	return s.
-		SetReferrer(APIEndpoints.CommunityBase.String() + "/mobilelogin?oauth_client_id=DE45CD61&oauth_scope=read_profile%20write_profile%20read_client%20write_client").
+		SetReferrer(s.client.Endpoints.CommunityBase.String() + "/mobilelogin?oauth_client_id=DE45CD61&oauth_scope=read_profile%20write_profile%20read_client%20write_client").
 		Do()
 }
 
+// checkStatus turns the http statuses and X-eresult headers steam
+// uses to say no into errors
+func checkStatus(r *http.Response) error {
+	switch r.StatusCode {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrSessionExpired
+	}
+
+	if eresult := responseEResult(r); eresult != EResultInvalid && eresult != EResultOK {
+		return &SteamError{Op: r.Request.URL.Path, EResult: eresult}
+	}
+
+	return nil
+}
+
 func (s *steamWeb) handleJSON(r *http.Response) error {
 	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
 		return errors.New("incorrect content type, expecting application/json")
@@ -174,3 +246,16 @@ func (s *steamWeb) handleJSON(r *http.Response) error {
 	decoder := json.NewDecoder(r.Body)
 	return decoder.Decode(s.oV)
 }
+
+func (s *steamWeb) handleProtobuf(r *http.Response) error {
+	if contentType := r.Header.Get("Content-Type"); strings.HasPrefix(contentType, "text/") || strings.HasPrefix(contentType, "application/json") {
+		return errors.New("incorrect content type, expecting protobuf")
+	}
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return s.oV.(protoUnmarshaler).unmarshalProto(b)
+}
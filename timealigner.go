@@ -5,60 +5,88 @@
 package steamauth
 
 import (
-	"net/url"
+	"context"
+	"sync"
 	"time"
 )
 
+// timeAligner is safe to share between goroutines, only one of
+// them will align the time with steam at once
 type timeAligner struct {
+	client         *Client
+	mu             sync.Mutex
 	aligned        bool
 	timeDifference time.Duration
 }
 
 // TimeAligner is used to synchronise your local time to the time
 // in steamservers so that the SteamGuard codes generated match
-// the expectations of Steam
-var TimeAligner = &timeAligner{}
+// the expectations of Steam, it belongs to the DefaultClient
+var TimeAligner = DefaultClient.TimeAligner()
 
 // GetSteamTime will returned the synchronised time, calling
-// `AlignTime` if required
+// `AlignTime` if required, if that fails it's the local time
 func (t *timeAligner) GetSteamTime() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if !t.aligned {
-		t.AlignTime()
+		t.alignTime(context.Background())
 	}
 
 	return time.Now().Add(t.timeDifference)
 }
 
+// GetSteamTimeContext is GetSteamTime with a context used should
+// alignment be required, unlike GetSteamTime it returns the error if
+// the time couldn't be aligned or the context is done
+func (t *timeAligner) GetSteamTimeContext(ctx context.Context) (time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.aligned {
+		if err := t.alignTime(ctx); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Now().Add(t.timeDifference), nil
+}
+
 // AlignTime will get the current time from steam and store
 // the offset internally for use later
 func (t *timeAligner) AlignTime() {
-	log("Synchronising time")
-	tsr := timeSyncResponse{}
-	_, err := SteamWeb().
-		Get(APIEndpoints.TwoFactorTimeQuery.String()).
-		SetParams(url.Values{"steamid": []string{"0"}}).
-		HandleJSON(&tsr).
+	t.AlignTimeContext(context.Background())
+}
+
+// AlignTimeContext is AlignTime with a context, it returns why the time
+// couldn't be aligned
+func (t *timeAligner) AlignTimeContext(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.alignTime(ctx)
+}
+
+func (t *timeAligner) alignTime(ctx context.Context) error {
+	t.client.log("Synchronising time")
+	tsr := twoFactorTimeResponse{}
+	_, err := t.client.SteamWeb().
+		SetContext(ctx).
+		Post(t.client.Endpoints.TwoFactorTimeQuery.String()).
+		SetProtobuf(&twoFactorTimeRequest{SenderTime: uint64(time.Now().Unix())}).
+		HandleProtobuf(&tsr).
 		Do()
 
 	if err != nil {
-		return
+		return err
 	}
 
-	t.timeDifference = tsr.Response.ServerTime.Sub(time.Now())
-	logf("Difference between server time and local is %s", t.timeDifference)
+	t.timeDifference = time.Unix(int64(tsr.ServerTime), 0).Sub(time.Now())
+	t.client.logf("Difference between server time and local is %s", t.timeDifference)
 	t.aligned = true
-}
-
-type timeSyncResponse struct {
-	Response struct {
-		ServerTime                 timestamp `json:"server_time"`
-		SkewTolerence              seconds   `json:"skew_tolerance_seconds"`
-		LargeTimeJink              seconds   `json:"large_time_jink"`
-		ProbeFrequency             seconds   `json:"probe_frequency_seconds"`
-		AdjustedTimeProbeFrequency seconds   `json:"adjusted_time_probe_frequency_seconds"`
-		HintProbeFrequency         seconds   `json:"hint_probe_frequency_seconds"`
-		SyncTimeout                seconds   `json:"sync_timeout"`
-		RetryDelay                 seconds   `json:"try_again_seconds"`
-		MaxAttempts                int       `json:"max_attempts"`
-	} `json:"response"`
+	return nil
 }
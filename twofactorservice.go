@@ -0,0 +1,221 @@
+// Copyright 2015 Shannon Wynter. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package steamauth
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// Hand rolled messages for the ITwoFactorService methods we use,
+// field numbers come from steammessages_twofactor.steamclient.proto
+
+// CTwoFactor_Time_Request
+type twoFactorTimeRequest struct {
+	SenderTime uint64
+}
+
+func (m *twoFactorTimeRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.varint(1, m.SenderTime)
+	return w
+}
+
+// CTwoFactor_Time_Response
+type twoFactorTimeResponse struct {
+	ServerTime                 uint64
+	SkewTolerance              uint64
+	LargeTimeJink              uint64
+	ProbeFrequency             uint32
+	AdjustedTimeProbeFrequency uint32
+	HintProbeFrequency         uint32
+	SyncTimeout                uint32
+	RetryDelay                 uint32
+	MaxAttempts                uint32
+}
+
+func (m *twoFactorTimeResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.ServerTime = f.u
+		case 2:
+			m.SkewTolerance = f.u
+		case 3:
+			m.LargeTimeJink = f.u
+		case 4:
+			m.ProbeFrequency = uint32(f.u)
+		case 5:
+			m.AdjustedTimeProbeFrequency = uint32(f.u)
+		case 6:
+			m.HintProbeFrequency = uint32(f.u)
+		case 7:
+			m.SyncTimeout = uint32(f.u)
+		case 8:
+			m.RetryDelay = uint32(f.u)
+		case 9:
+			m.MaxAttempts = uint32(f.u)
+		}
+		return nil
+	})
+}
+
+// CTwoFactor_AddAuthenticator_Request
+type twoFactorAddAuthenticatorRequest struct {
+	SteamID           SteamID
+	AuthenticatorTime uint64
+	AuthenticatorType uint32
+	DeviceIdentifier  string
+	SMSPhoneID        string
+}
+
+func (m *twoFactorAddAuthenticatorRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.fixed64(1, uint64(m.SteamID))
+	w.varint(2, m.AuthenticatorTime)
+	w.varint(4, uint64(m.AuthenticatorType))
+	w.string(5, m.DeviceIdentifier)
+	w.string(6, m.SMSPhoneID)
+	return w
+}
+
+// CTwoFactor_AddAuthenticator_Response
+type twoFactorAddAuthenticatorResponse struct {
+	SharedSecret   []byte
+	SerialNumber   uint64
+	RevocationCode string
+	URI            string
+	ServerTime     uint64
+	AccountName    string
+	TokenGID       string
+	IdentitySecret []byte
+	Secret1        []byte
+	Status         EResult
+}
+
+func (m *twoFactorAddAuthenticatorResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.SharedSecret = f.bytes()
+		case 2:
+			m.SerialNumber = f.u
+		case 3:
+			m.RevocationCode = f.string()
+		case 4:
+			m.URI = f.string()
+		case 5:
+			m.ServerTime = f.u
+		case 6:
+			m.AccountName = f.string()
+		case 7:
+			m.TokenGID = f.string()
+		case 8:
+			m.IdentitySecret = f.bytes()
+		case 9:
+			m.Secret1 = f.bytes()
+		case 10:
+			m.Status = EResult(f.int32())
+		}
+		return nil
+	})
+}
+
+// account converts the response into the SteamGuardAccount
+// shape everyone saves their secrets in
+func (m *twoFactorAddAuthenticatorResponse) account() SteamGuardAccount {
+	return SteamGuardAccount{
+		SharedSecret:   base64.StdEncoding.EncodeToString(m.SharedSecret),
+		SerialNumber:   strconv.FormatUint(m.SerialNumber, 10),
+		RevocationCode: m.RevocationCode,
+		URI:            m.URI,
+		ServerTime:     timestamp{time.Unix(int64(m.ServerTime), 0)},
+		AccountName:    m.AccountName,
+		TokenGID:       m.TokenGID,
+		IdentitySecret: base64.StdEncoding.EncodeToString(m.IdentitySecret),
+		Secret1:        base64.StdEncoding.EncodeToString(m.Secret1),
+		Status:         m.Status,
+	}
+}
+
+// CTwoFactor_FinalizeAddAuthenticator_Request
+type twoFactorFinalizeAddAuthenticatorRequest struct {
+	SteamID           SteamID
+	AuthenticatorCode string
+	AuthenticatorTime uint64
+	ActivationCode    string
+	ValidateSMSCode   bool
+}
+
+func (m *twoFactorFinalizeAddAuthenticatorRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.fixed64(1, uint64(m.SteamID))
+	w.string(2, m.AuthenticatorCode)
+	w.varint(3, m.AuthenticatorTime)
+	w.string(4, m.ActivationCode)
+	w.bool(6, m.ValidateSMSCode)
+	return w
+}
+
+// CTwoFactor_FinalizeAddAuthenticator_Response
+type twoFactorFinalizeAddAuthenticatorResponse struct {
+	Success    bool
+	WantMore   bool
+	ServerTime uint64
+	Status     EResult
+}
+
+func (m *twoFactorFinalizeAddAuthenticatorResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Success = f.bool()
+		case 2:
+			m.WantMore = f.bool()
+		case 3:
+			m.ServerTime = f.u
+		case 4:
+			m.Status = EResult(f.int32())
+		}
+		return nil
+	})
+}
+
+// CTwoFactor_RemoveAuthenticator_Request
+type twoFactorRemoveAuthenticatorRequest struct {
+	RevocationCode   string
+	RevocationReason uint32
+	SteamGuardScheme uint32
+}
+
+func (m *twoFactorRemoveAuthenticatorRequest) marshalProto() []byte {
+	w := protoWriter{}
+	w.string(2, m.RevocationCode)
+	w.varint(5, uint64(m.RevocationReason))
+	w.varint(6, uint64(m.SteamGuardScheme))
+	return w
+}
+
+// CTwoFactor_RemoveAuthenticator_Response
+type twoFactorRemoveAuthenticatorResponse struct {
+	Success                     bool
+	ServerTime                  uint64
+	RevocationAttemptsRemaining uint32
+}
+
+func (m *twoFactorRemoveAuthenticatorResponse) unmarshalProto(b []byte) error {
+	return rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Success = f.bool()
+		case 3:
+			m.ServerTime = f.u
+		case 5:
+			m.RevocationAttemptsRemaining = uint32(f.u)
+		}
+		return nil
+	})
+}
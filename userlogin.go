@@ -5,6 +5,7 @@
 package steamauth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
@@ -44,17 +45,14 @@ type UserLogin struct {
 	Session  *SessionData
 	LoggedIn bool
 
+	client    *Client
 	cookieJar *cookiejar.Jar
 }
 
-// NewUserLogin allocates and returns a new UserLogin.
+// NewUserLogin allocates and returns a new UserLogin
+// bound to the DefaultClient.
 func NewUserLogin(username, password string) *UserLogin {
-	cookieJar, _ := cookiejar.New(&cookiejar.Options{})
-	return &UserLogin{
-		cookieJar: cookieJar,
-		Session:   &SessionData{},
-		Username:  username,
-		Password:  password}
+	return DefaultClient.NewUserLogin(username, password)
 }
 
 // DoLogin actually attempt to login.
@@ -63,28 +61,37 @@ func NewUserLogin(username, password string) *UserLogin {
 // Encrypts your password.
 // Attempts to authenticate.
 func (u *UserLogin) DoLogin() (LoginResult, error) {
+	return u.DoLoginContext(context.Background())
+}
+
+// DoLoginContext is DoLogin with a context that can cancel
+// any in-flight requests to steam
+func (u *UserLogin) DoLoginContext(ctx context.Context) (LoginResult, error) {
 	postData := url.Values{}
 	cookieJar := u.cookieJar
+	c := u.client
 
-	if len(cookieJar.Cookies(APIEndpoints.CommunityBase)) == 0 {
-		log("Creating new 'empty' sesson")
-		u.Session.SetCookies(cookieJar)
+	if len(cookieJar.Cookies(c.Endpoints.CommunityBase)) == 0 {
+		c.log("Creating new 'empty' sesson")
+		c.setSessionCookies(u.Session, cookieJar)
 
-		SteamWeb().
+		c.SteamWeb().
+			SetContext(ctx).
 			SetJar(cookieJar).
 			AddHeader("X-Requested-With", "com.valvesoftware.android.steam.community").
-			Get(APIEndpoints.CommunityBase.String() + "/login?oauth_client_id=DE45CD61&oauth_scope=read_profile%20write_profile%20read_client%20write_client").
+			Get(c.Endpoints.CommunityBase.String() + "/login?oauth_client_id=DE45CD61&oauth_scope=read_profile%20write_profile%20read_client%20write_client").
 			MobileLoginRequest()
 	}
 
-	logf("Retriving RSAKey for %s", u.Username)
+	c.logf("Retriving RSAKey for %s", u.Username)
 
 	postData.Set("username", u.Username)
 	rsaResponse := rsaResponse{}
-	_, err := SteamWeb().
+	_, err := c.SteamWeb().
+		SetContext(ctx).
 		SetJar(cookieJar).
 		SetParams(postData).
-		Post(APIEndpoints.CommunityBase.String() + "/login/getrsakey").
+		Post(c.Endpoints.CommunityBase.String() + "/login/getrsakey").
 		HandleJSON(&rsaResponse).
 		MobileLoginRequest()
 
@@ -114,64 +121,65 @@ func (u *UserLogin) DoLogin() (LoginResult, error) {
 	postData.Set("loginfriendlyname", "#login_emailauth_friendlyname_mobile")
 	postData.Set("donotcache", strconv.FormatInt(time.Now().Unix(), 10))
 
-	logf("Attempting to authenticate as %s", u.Username)
+	c.logf("Attempting to authenticate as %s", u.Username)
 	loginResponse := LoginResponse{}
-	_, err = SteamWeb().
+	_, err = c.SteamWeb().
+		SetContext(ctx).
 		SetJar(cookieJar).
 		SetParams(postData).
-		Post(APIEndpoints.CommunityBase.String() + "/login/dologin").
+		Post(c.Endpoints.CommunityBase.String() + "/login/dologin").
 		HandleJSON(&loginResponse).
 		MobileLoginRequest()
 
 	if err != nil {
-		logf("Protocol error %s", err)
+		c.logf("Protocol error %s", err)
 		return LoginGeneralFailure, err
 	}
 
 	if loginResponse.CaptchaNeeded {
-		log(NeedCaptcha)
+		c.log(NeedCaptcha)
 		u.RequiresCaptcha = true
 		u.CaptchaGID = loginResponse.CaptchaGID
 		return NeedCaptcha, nil
 	}
 
 	if loginResponse.EmailAuthNeeded {
-		log(NeedEmail)
+		c.log(NeedEmail)
 		u.RequiresEmail = true
 		u.SteamID = loginResponse.SteamID
-		logf("Have steamid... it is %#v", u.SteamID)
+		c.logf("Have steamid... it is %#v", u.SteamID)
 		return NeedEmail, nil
 	}
 
 	if loginResponse.TwoFactorNeeded && !loginResponse.Success {
-		log(Need2FA)
+		c.log(Need2FA)
 		u.Requires2FA = true
 		return Need2FA, nil
 	}
 
 	if !loginResponse.LoginComplete {
-		log(BadCredentials)
+		c.log(BadCredentials)
 		if loginResponse.Message != "" {
-			log(loginResponse)
+			c.log(loginResponse)
 			return BadCredentials, errors.New(loginResponse.Message)
 		}
 		return BadCredentials, nil
 	}
 
 	if loginResponse.OAuth == nil || len(loginResponse.OAuth.OAuthToken) == 0 {
-		logf("Protocol error: missing oath")
+		c.logf("Protocol error: missing oath")
 		return LoginGeneralFailure, errors.New("missing oauth")
 	}
 
 	var sessionCookie *http.Cookie
-	for _, cookie := range cookieJar.Cookies(APIEndpoints.CommunityBase) {
+	for _, cookie := range cookieJar.Cookies(c.Endpoints.CommunityBase) {
 		if cookie.Name == "sessionid" {
 			sessionCookie = cookie
 			break
 		}
 	}
 	if sessionCookie == nil {
-		logf("Protocol error: missing session cookie")
+		c.logf("Protocol error: missing session cookie")
 		return LoginGeneralFailure, errors.New("missing session cookie")
 	}
 
@@ -184,13 +192,13 @@ func (u *UserLogin) DoLogin() (LoginResult, error) {
 		SessionID:        sessionCookie.Value,
 	}
 
-	log(LoginOkay)
+	c.log(LoginOkay)
 	return LoginOkay, nil
 }
 
 // CaptchaURL returns a fully qualified URL to a given captcha GID
 func (u *UserLogin) CaptchaURL() string {
-	return u.CaptchaGID.URL()
+	return u.CaptchaGID.urlFor(u.client.Endpoints.CommunityBase)
 }
 
 // LoginResponse represents the response sent from Steam servers.
@@ -263,18 +271,25 @@ func (r *rsaResponse) UnmarshalJSON(b []byte) error {
 		return nil
 	}
 
-	exponent, err := strconv.ParseInt(localData.Exponent, 16, 0)
+	r.PublicKey, err = parseRSAPublicKey(localData.Modulus, localData.Exponent)
+	return err
+}
+
+// parseRSAPublicKey from the hex encoded modulus and exponent steam sends
+func parseRSAPublicKey(mod, exp string) (*rsa.PublicKey, error) {
+	exponent, err := strconv.ParseInt(exp, 16, 0)
+	if err != nil {
+		return nil, errors.New("invalid exponent")
+	}
 	modulus := big.Int{}
-	if _, ok := modulus.SetString(localData.Modulus, 16); !ok {
-		return errors.New("invalid modulus")
+	if _, ok := modulus.SetString(mod, 16); !ok {
+		return nil, errors.New("invalid modulus")
 	}
 
-	r.PublicKey = &rsa.PublicKey{
+	return &rsa.PublicKey{
 		N: &modulus,
 		E: int(exponent),
-	}
-
-	return nil
+	}, nil
 }
 
 // LoginResult is the type of login result.
@@ -6,7 +6,10 @@ package steamauth
 
 import (
 	"encoding/json"
-	"strconv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 )
 
 // God I'm lazy
@@ -15,17 +18,6 @@ func unmarshalStringyValue(b []byte) (s string, err error) {
 	return
 }
 
-// see previous statement x2
-func unmarshalStringyInt(b []byte) (i int64, err error) {
-	s := ""
-	s, err = unmarshalStringyValue(b)
-	if err != nil {
-		return
-	}
-	i, err = strconv.ParseInt(s, 10, 64)
-	return
-}
-
 // you get the point ;)
 func iif(cond bool, a, b string) string {
 	if cond {
@@ -33,3 +25,71 @@ func iif(cond bool, a, b string) string {
 	}
 	return b
 }
+
+// writeFileAtomic writes to a synced temporary file and renames it over
+// path so a crash leaves either the old or the new file behind, never a
+// half written or empty one. An existing file keeps its mode
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := writeTempFile(path, b)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return renameSynced(tmp, path)
+}
+
+// writeTempFile writes b to a synced temporary file next to path, with
+// the mode of path if it exists, and returns its name
+func writeTempFile(path string, b []byte) (string, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeSynced(tmp, path, b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// writeSynced writes b to f with the mode of path if it exists and syncs it
+func writeSynced(f *os.File, path string, b []byte) error {
+	if fi, err := os.Stat(path); err == nil {
+		if err := f.Chmod(fi.Mode().Perm()); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// renameSynced renames from over to and syncs the directory so the
+// rename itself survives a crash
+func renameSynced(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(to))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	// windows can't sync a directory, renames there are already durable
+	if err := dir.Sync(); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}